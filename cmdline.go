@@ -5,46 +5,220 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/tebeka/go2xunit/lib"
 )
 
+// stringList is a flag that can be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends a value
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 var args struct {
-	inFile      string
+	inFiles     stringList
 	outFile     string
 	fail        bool
 	showVersion bool
+	format      string
 	bambooOut   bool
 	xunitnetOut bool
+	xunitnet2   bool
+	nunitOut    bool
+	trxOut      bool
+	tapOut      bool
+	teamcityOut bool
+	sonarOut    bool
+	nested      bool
 	isGocheck   bool
+	isJSON      bool
 	suitePrefix string
+	run         bool
+	merge       bool
+	passthrough bool
+	files       []string // merge files
+
+	githubActions bool
+	markdownFile  string
+	htmlFile      string
+	jsonOutFile   string
+	jsonOutFormat string
+	allureDir     string
+	templateFile  string
 }
 
 func init() {
-	flag.StringVar(&args.inFile, "input", "", "input file (default to stdin)")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: %s [flags]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s run [flags] -- command [args...]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s merge [flags] file.xml [file.xml...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Var(&args.inFiles, "input",
+		"input file or glob, can be given more than once (default to stdin)")
 	flag.StringVar(&args.outFile, "output", "", "output file (default to stdout)")
 	flag.BoolVar(&args.fail, "fail", false, "fail (non zero exit) if any test failed")
 	flag.BoolVar(&args.showVersion, "version", false, "print version and exit")
+	flag.StringVar(&args.format, "format", "",
+		"report format (default xunit), \"-format help\" lists the formats")
+	flag.StringVar(&args.templateFile, "template", "",
+		"write the report with a text/template file (executed with lib.TestResults)")
 	flag.BoolVar(&args.bambooOut, "bamboo", false,
 		"xml compatible with Atlassian's Bamboo")
-	flag.BoolVar(&args.xunitnetOut, "xunitnet", false, "xml compatible with xunit.net")
+	flag.BoolVar(&args.xunitnetOut, "xunitnet", false, "xml compatible with xunit.net (v1)")
+	flag.BoolVar(&args.xunitnet2, "xunitnet2", false, "xml compatible with xunit.net v2")
+	flag.BoolVar(&args.nunitOut, "nunit", false, "xml compatible with NUnit 3")
+	flag.BoolVar(&args.trxOut, "trx", false,
+		"Visual Studio test results (.trx), used by Azure Pipelines")
+	flag.BoolVar(&args.tapOut, "tap", false, "TAP (Test Anything Protocol) output")
+	flag.BoolVar(&args.teamcityOut, "teamcity", false,
+		"TeamCity service messages (streamed with go test -v output)")
+	flag.BoolVar(&args.sonarOut, "sonar", false,
+		"SonarQube generic test execution report (run in the module directory)")
+	flag.BoolVar(&args.githubActions, "github-actions", false,
+		"also print GitHub Actions error annotations for failed tests")
+	flag.StringVar(&args.markdownFile, "markdown", "",
+		"also write a markdown summary to file (e.g. $GITHUB_STEP_SUMMARY)")
+	flag.StringVar(&args.htmlFile, "html", "", "also write an HTML report to file")
+	flag.StringVar(&args.jsonOutFile, "json-out", "", "also write a JSON report to file")
+	flag.StringVar(&args.jsonOutFormat, "json-out-format", "go2xunit",
+		"-json-out format: go2xunit or ctrf (Common Test Report Format)")
+	flag.StringVar(&args.allureDir, "allure", "", "also write Allure results to directory")
+	flag.BoolVar(&args.nested, "nested", false,
+		"nest sub tests in a testsuite of their parent test")
 	flag.BoolVar(&args.isGocheck, "gocheck", false, "parse gocheck output")
+	flag.BoolVar(&args.isJSON, "json", false, "parse go test -json output")
 	flag.BoolVar(&lib.Options.FailOnRace, "fail-on-race", false,
 		"mark test as failing if it exposes a data race")
 	flag.StringVar(&args.suitePrefix, "suite-name-prefix", "",
 		"prefix to include before all suite names")
+	flag.BoolVar(&args.passthrough, "passthrough", false,
+		"copy input to stdout (stderr if output is stdout)")
+}
 
-	flag.Parse()
+// parseArgs parses the command line. "go2xunit run [flags] -- command" will
+// run command and parse its output, "go2xunit merge [flags] file..." will
+// merge xunit XML files
+func parseArgs() {
+	argv := os.Args[1:]
+	if len(argv) > 0 {
+		switch argv[0] {
+		case "run":
+			args.run = true
+			argv = argv[1:]
+		case "merge":
+			args.merge = true
+			args.files = parseInterspersed(argv[1:])
+			return
+		}
+	}
+	flag.CommandLine.Parse(argv)
+}
+
+// parseInterspersed parses flags that might come after positional arguments
+// (e.g. "a.xml b.xml -output all.xml"), returns the positional arguments
+func parseInterspersed(argv []string) []string {
+	var positional []string
+	for {
+		flag.CommandLine.Parse(argv)
+		argv = flag.Args()
+		if len(argv) == 0 {
+			return positional
+		}
+		positional = append(positional, argv[0])
+		argv = argv[1:]
+	}
+}
+
+// outputFormats returns the output format flags given
+func outputFormats() []string {
+	var formats []string
+	if args.format != "" {
+		formats = append(formats, "-format")
+	}
+	if args.templateFile != "" {
+		formats = append(formats, "-template")
+	}
+	if args.bambooOut {
+		formats = append(formats, "-bamboo")
+	}
+	if args.xunitnetOut {
+		formats = append(formats, "-xunitnet")
+	}
+	if args.xunitnet2 {
+		formats = append(formats, "-xunitnet2")
+	}
+	if args.nunitOut {
+		formats = append(formats, "-nunit")
+	}
+	if args.trxOut {
+		formats = append(formats, "-trx")
+	}
+	if args.tapOut {
+		formats = append(formats, "-tap")
+	}
+	if args.teamcityOut {
+		formats = append(formats, "-teamcity")
+	}
+	if args.sonarOut {
+		formats = append(formats, "-sonar")
+	}
+	return formats
 }
 
 // validateArgs validates command line arguments
 func validateArgs() error {
-	if flag.NArg() > 0 {
+	if args.run {
+		if flag.NArg() == 0 {
+			return fmt.Errorf("run: missing command (e.g. run -- go test -v ./...)")
+		}
+		if len(args.inFiles) > 0 {
+			return fmt.Errorf("run: can't use -input")
+		}
+	} else if args.merge {
+		if len(args.files) == 0 {
+			return fmt.Errorf("merge: missing files (e.g. merge a.xml b.xml)")
+		}
+		if len(args.inFiles) > 0 || args.isGocheck || args.isJSON {
+			return fmt.Errorf("merge: can't use -input, -gocheck or -json")
+		}
+	} else if flag.NArg() > 0 {
 		return fmt.Errorf("%s does not take parameters (did you mean -input?)", os.Args[0])
 	}
 
-	if args.bambooOut && args.xunitnetOut {
-		return fmt.Errorf("-bamboo and -xunitnet are mutually exclusive")
+	formats := outputFormats()
+	if len(formats) > 1 {
+		return fmt.Errorf("%s are mutually exclusive", strings.Join(formats, " and "))
+	}
+
+	if _, ok := lib.GetWriter(reportFormat()); !ok {
+		return fmt.Errorf("unknown format - %s (see -format help)", args.format)
+	}
+
+	// -nested is only supported by xunit output
+	format := reportFormat()
+	if args.nested && args.templateFile != "" {
+		return fmt.Errorf("-nested can't be used with -template")
+	}
+	if args.nested && format != "xunit" && format != "bamboo" && format != "xunit-nested" {
+		return fmt.Errorf("-nested can't be used with %s format", format)
+	}
+
+	if args.jsonOutFormat != "go2xunit" && args.jsonOutFormat != "ctrf" {
+		return fmt.Errorf("unknown -json-out-format - %s", args.jsonOutFormat)
+	}
+
+	if args.isGocheck && args.isJSON {
+		return fmt.Errorf("-gocheck and -json are mutually exclusive")
 	}
 
 	return nil
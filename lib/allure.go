@@ -0,0 +1,146 @@
+package lib
+
+// Allure results
+// see https://allurereport.org/docs/how-it-works-test-result-file/
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type allureLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type allureStatusDetails struct {
+	Message string `json:"message,omitempty"`
+	Trace   string `json:"trace,omitempty"`
+}
+
+type allureAttachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+type allureResult struct {
+	UUID          string              `json:"uuid"`
+	HistoryID     string              `json:"historyId"`
+	Name          string              `json:"name"`
+	FullName      string              `json:"fullName"`
+	Status        string              `json:"status"`
+	StatusDetails allureStatusDetails `json:"statusDetails"`
+	Stage         string              `json:"stage"`
+	Start         int64               `json:"start"`
+	Stop          int64               `json:"stop"`
+	Labels        []allureLabel       `json:"labels"`
+	Attachments   []allureAttachment  `json:"attachments"`
+}
+
+// allureStatus returns the Allure status of a test, errored tests are broken
+func allureStatus(status Status) string {
+	switch status {
+	case Passed:
+		return "passed"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	}
+	return "broken"
+}
+
+// unixMillis returns t in milliseconds since the epoch
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// allureLabels returns the labels of test, the package is the suite and sub
+// tests are in a sub suite of their root test
+func allureLabels(suite *Suite, test *Test) []allureLabel {
+	labels := []allureLabel{
+		{"framework", "go test"},
+		{"language", "go"},
+		{"package", suite.Name},
+		{"suite", suite.Name},
+	}
+	if parent := path.Dir(suite.Name); parent != "." && parent != "/" {
+		labels = append(labels, allureLabel{"parentSuite", parent})
+	}
+	if i := strings.Index(test.Name, "/"); i != -1 {
+		labels = append(labels, allureLabel{"subSuite", test.Name[:i]})
+	}
+	return labels
+}
+
+// writeAllureAttachment writes output to dir, returns the attachment
+func writeAllureAttachment(dir, uuid, name, output string) (allureAttachment, error) {
+	source := fmt.Sprintf("%s-%s-attachment.txt", uuid, name)
+	err := ioutil.WriteFile(filepath.Join(dir, source), []byte(output), 0644)
+	return allureAttachment{Name: name, Source: source, Type: "text/plain"}, err
+}
+
+// WriteAllure writes a "<uuid>-result.json" file (and output attachments) for
+// every test to dir, the directory is created if needed
+func WriteAllure(suites []*Suite, dir string, testTime time.Time) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	start := unixMillis(testTime)
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			fullName := suite.Name + "." + test.Name
+			result := allureResult{
+				UUID:      guid("allure", fullName, testTime.String()),
+				HistoryID: fmt.Sprintf("%x", md5.Sum([]byte(fullName))),
+				Name:      test.Name,
+				FullName:  fullName,
+				Status:    allureStatus(test.Status),
+				Stage:     "finished",
+				Start:     start,
+				Stop:      unixMillis(addTime(testTime, test.Time)),
+				Labels:    allureLabels(suite, test),
+			}
+			if test.Status != Passed {
+				result.StatusDetails.Message = test.Message
+				result.StatusDetails.Trace = stackTrace(test.Message)
+			}
+
+			result.Attachments = []allureAttachment{}
+			outputs := []struct{ name, output string }{
+				{"output", test.Output},
+				{"errors", test.ErrOutput},
+			}
+			for _, out := range outputs {
+				if out.output == "" {
+					continue
+				}
+				attachment, err := writeAllureAttachment(dir, result.UUID, out.name, out.output)
+				if err != nil {
+					return err
+				}
+				result.Attachments = append(result.Attachments, attachment)
+			}
+
+			file, err := os.Create(filepath.Join(dir, result.UUID+"-result.json"))
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(result, file); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package lib
+
+// GitHub Actions workflow commands
+// see https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ghEscapeData escapes workflow command data (the message)
+func ghEscapeData(s string) string {
+	s = strings.Replace(s, "%", "%25", -1)
+	s = strings.Replace(s, "\r", "%0D", -1)
+	return strings.Replace(s, "\n", "%0A", -1)
+}
+
+// ghEscapeProperty escapes workflow command property values
+func ghEscapeProperty(s string) string {
+	s = ghEscapeData(s)
+	s = strings.Replace(s, ":", "%3A", -1)
+	return strings.Replace(s, ",", "%2C", -1)
+}
+
+// annotationFile returns file path relative to root (the module directory).
+// module is the Go module path, used to find the package directory of relative
+// file names ("mmath_test.go").
+func annotationFile(file, pkg, module, root string) string {
+	if filepath.IsAbs(file) {
+		if root, err := filepath.Abs(root); err == nil {
+			if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel)
+			}
+		}
+		return file
+	}
+
+	if module != "" && !strings.Contains(file, "/") && strings.HasPrefix(pkg, module+"/") {
+		return path.Join(strings.TrimPrefix(pkg, module+"/"), file)
+	}
+	return file
+}
+
+// WriteGitHubAnnotations writes an "::error" workflow command for every failed
+// test, GitHub shows these inline on the pull request diff. module is the Go
+// module path ("" if unknown).
+func WriteGitHubAnnotations(suites []*Suite, out io.Writer, module string) error {
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.IsParent() || (test.Status != Failed && test.Status != Errored) {
+				continue
+			}
+
+			var props []string
+			if file, line := fileLine(test.Message); file != "" {
+				file = annotationFile(file, suite.Name, module, ".")
+				props = append(props,
+					"file="+ghEscapeProperty(file),
+					fmt.Sprintf("line=%d", line))
+			}
+			title := fmt.Sprintf("%s %s", test.Name, strings.ToLower(test.Status.String()))
+			props = append(props, "title="+ghEscapeProperty(title))
+
+			message := strings.TrimSpace(test.Message)
+			if message == "" {
+				message = title
+			}
+			_, err := fmt.Fprintf(out, "::error %s::%s\n", strings.Join(props, ","), ghEscapeData(message))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package lib
+
+// HTML output
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// HTMLTemplate is the template for a self contained HTML report (no external
+// resources)
+const HTMLTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Test Results - {{.Assembly}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+h1 { font-size: 1.5em; }
+.totals span { margin-right: 1em; }
+.controls { margin: 1em 0; }
+.controls label { margin-right: 1em; }
+details.suite { border: 1px solid #d1d5da; border-radius: 4px; margin-bottom: 0.5em; }
+details.suite > summary { padding: 0.5em; cursor: pointer; background: #f6f8fa; }
+table { border-collapse: collapse; width: 100%; }
+td { padding: 0.25em 0.5em; border-top: 1px solid #eaecef; vertical-align: top; }
+td.time { text-align: right; white-space: nowrap; width: 6em; }
+td.status { white-space: nowrap; width: 6em; font-weight: bold; }
+pre { background: #f6f8fa; padding: 0.5em; overflow-x: auto; margin: 0.25em 0; }
+.Passed .status, .passed { color: #22863a; }
+.Failed .status, .failed { color: #cb2431; }
+.Errored .status, .errored { color: #b31d28; }
+.Skipped .status, .skipped { color: #6a737d; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>Test Results - {{.Assembly}}</h1>
+<p class="totals">
+  <span><b>{{.Len}}</b> tests</span>
+  <span class="passed">{{.NumPassed}} passed</span>
+  <span class="failed">{{.NumFailed}} failed</span>
+  <span class="errored">{{.NumErrored}} errored</span>
+  <span class="skipped">{{.NumSkipped}} skipped</span>
+  <span>{{.Time}}s</span>
+  <span>{{.RunDate}} {{.RunTime}}</span>
+</p>
+<div class="controls">
+  <label><input type="checkbox" class="filter" value="Passed" checked> Passed</label>
+  <label><input type="checkbox" class="filter" value="Failed" checked> Failed</label>
+  <label><input type="checkbox" class="filter" value="Errored" checked> Errored</label>
+  <label><input type="checkbox" class="filter" value="Skipped" checked> Skipped</label>
+  <input type="search" id="search" placeholder="Search tests">
+</div>
+{{range $suite := .Suites}}<details class="suite"{{if or $suite.NumFailed $suite.NumErrored}} open{{end}}>
+<summary><b>{{$suite.Name}}</b> - {{$suite.Len}} tests, {{$suite.NumPassed}} passed, {{$suite.NumFailed}} failed, {{$suite.NumErrored}} errored, {{$suite.NumSkipped}} skipped ({{$suite.Time}}s)</summary>
+<table>
+{{range $test := $suite.Tests}}<tr class="test {{$test.Status}}" data-name="{{lower $test.Name}}">
+  <td class="status">{{$test.Status}}</td>
+  <td>{{$test.Name}}{{if and $test.Message (ne $test.Status $.Passed)}}
+    <pre>{{$test.Message}}</pre>{{end}}{{if $test.Output}}
+    <details><summary>Output</summary><pre>{{$test.Output}}</pre></details>{{end}}</td>
+  <td class="time">{{$test.Time}}s</td>
+</tr>
+{{end}}</table>
+</details>
+{{end}}<script>
+(function() {
+  var filters = document.querySelectorAll(".filter");
+  var search = document.getElementById("search");
+
+  function update() {
+    var shown = {};
+    for (var i = 0; i < filters.length; i++) {
+      shown[filters[i].value] = filters[i].checked;
+    }
+    var text = search.value.toLowerCase();
+    var suites = document.querySelectorAll("details.suite");
+    for (var i = 0; i < suites.length; i++) {
+      var rows = suites[i].querySelectorAll("tr.test");
+      var visible = 0;
+      for (var j = 0; j < rows.length; j++) {
+        var row = rows[j];
+        var status = row.className.split(" ")[1];
+        var match = shown[status] && row.getAttribute("data-name").indexOf(text) !== -1;
+        row.classList.toggle("hidden", !match);
+        if (match) {
+          visible++;
+        }
+      }
+      suites[i].classList.toggle("hidden", visible === 0);
+    }
+  }
+
+  for (var i = 0; i < filters.length; i++) {
+    filters[i].addEventListener("change", update);
+  }
+  search.addEventListener("input", update);
+})();
+</script>
+</body>
+</html>
+`
+
+// WriteHTML writes a self contained HTML report of suites to out
+func WriteHTML(suites []*Suite, out io.Writer, testTime time.Time) error {
+	t, err := template.New("html").Funcs(template.FuncMap{
+		"lower": strings.ToLower,
+	}).Parse(HTMLTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(out, newTestResults(suites, testTime))
+}
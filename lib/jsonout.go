@@ -0,0 +1,166 @@
+package lib
+
+// JSON output
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// JSONVersion is the version of the JSON report schema, it changes only on
+// incompatible changes
+const JSONVersion = 1
+
+// JSONTotals are the totals of all suites
+type JSONTotals struct {
+	Tests   int    `json:"tests"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+	Errored int    `json:"errored"`
+	Time    string `json:"time"`
+}
+
+// JSONReport is the JSON report of suites
+type JSONReport struct {
+	Version int        `json:"version"`
+	Start   time.Time  `json:"start"`
+	Totals  JSONTotals `json:"totals"`
+	Suites  []*Suite   `json:"suites"`
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(v interface{}, out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// WriteJSON writes suites to out as a JSONReport
+func WriteJSON(suites []*Suite, out io.Writer, testTime time.Time) error {
+	results := newTestResults(suites, testTime)
+	report := &JSONReport{
+		Version: JSONVersion,
+		Start:   testTime,
+		Totals: JSONTotals{
+			Tests:   results.Len,
+			Passed:  results.NumPassed,
+			Failed:  results.NumFailed,
+			Skipped: results.NumSkipped,
+			Errored: results.NumErrored,
+			Time:    results.Time,
+		},
+		Suites: suites,
+	}
+	return writeJSON(report, out)
+}
+
+// CTRF (Common Test Report Format)
+// see https://ctrf.io/docs/specification/overview
+
+type ctrfTool struct {
+	Name string `json:"name"`
+}
+
+type ctrfSummary struct {
+	Tests   int   `json:"tests"`
+	Passed  int   `json:"passed"`
+	Failed  int   `json:"failed"`
+	Pending int   `json:"pending"`
+	Skipped int   `json:"skipped"`
+	Other   int   `json:"other"`
+	Start   int64 `json:"start"`
+	Stop    int64 `json:"stop"`
+}
+
+type ctrfTest struct {
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	Duration  int64    `json:"duration"`
+	RawStatus string   `json:"rawStatus,omitempty"`
+	Message   string   `json:"message,omitempty"`
+	Trace     string   `json:"trace,omitempty"`
+	Suite     string   `json:"suite,omitempty"`
+	Stdout    []string `json:"stdout,omitempty"`
+	Stderr    []string `json:"stderr,omitempty"`
+}
+
+type ctrfResults struct {
+	Tool    ctrfTool    `json:"tool"`
+	Summary ctrfSummary `json:"summary"`
+	Tests   []ctrfTest  `json:"tests"`
+}
+
+type ctrfReport struct {
+	ReportFormat string      `json:"reportFormat"`
+	SpecVersion  string      `json:"specVersion"`
+	Results      ctrfResults `json:"results"`
+}
+
+// ctrfStatus returns the CTRF status of a test, errored tests are failed
+func ctrfStatus(status Status) string {
+	switch status {
+	case Passed:
+		return "passed"
+	case Failed, Errored:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	}
+	return "other"
+}
+
+// splitLines splits output to lines (nil if empty)
+func splitLines(output string) []string {
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// WriteCTRF writes suites to out in CTRF (Common Test Report Format)
+func WriteCTRF(suites []*Suite, out io.Writer, testTime time.Time) error {
+	results := newTestResults(suites, testTime)
+	report := &ctrfReport{
+		ReportFormat: "CTRF",
+		SpecVersion:  "0.0.0",
+		Results: ctrfResults{
+			Tool: ctrfTool{Name: "go2xunit"},
+			Summary: ctrfSummary{
+				Passed:  results.NumPassed,
+				Failed:  results.NumFailed + results.NumErrored,
+				Skipped: results.NumSkipped,
+				Start:   unixMillis(testTime),
+				Stop:    unixMillis(addTime(testTime, results.Time)),
+			},
+			Tests: []ctrfTest{},
+		},
+	}
+
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			ct := ctrfTest{
+				Name:     test.Name,
+				Status:   ctrfStatus(test.Status),
+				Duration: millis(test.Time),
+				Suite:    suite.Name,
+				Stdout:   splitLines(test.Output),
+				Stderr:   splitLines(test.ErrOutput),
+			}
+			if test.Status == Errored {
+				ct.RawStatus = "errored"
+			}
+			if test.Status != Passed {
+				ct.Message = test.Message
+				ct.Trace = stackTrace(test.Message)
+			}
+			if ct.Status == "other" {
+				report.Results.Summary.Other++
+			}
+			report.Results.Tests = append(report.Results.Tests, ct)
+		}
+	}
+	report.Results.Summary.Tests = len(report.Results.Tests)
+	return writeJSON(report, out)
+}
@@ -0,0 +1,263 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// GotestEvent is a single event emitted by "go test -json" (see "go doc
+// test2json")
+type GotestEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+
+	// "frame", "error" or "error-continue" (go 1.25+)
+	OutputType string
+
+	// Build events (go 1.24+)
+	ImportPath  string
+	FailedBuild string
+}
+
+// jsonPackage is the parsing state of a single package
+type jsonPackage struct {
+	suite     *Suite
+	tests     map[string]*Test
+	output    map[string]*strings.Builder
+	errors    map[string]*strings.Builder // t.Error, t.Fatal ... output
+	pkgOutput []string                    // output not belonging to any test
+}
+
+func newJSONPackage(name string) *jsonPackage {
+	return &jsonPackage{
+		suite:  &Suite{Name: name},
+		tests:  make(map[string]*Test),
+		output: make(map[string]*strings.Builder),
+		errors: make(map[string]*strings.Builder),
+	}
+}
+
+// test returns the test called name, creating it if it wasn't seen yet
+func (pkg *jsonPackage) test(name string) *Test {
+	test, ok := pkg.tests[name]
+	if !ok {
+		test = &Test{Name: name}
+		if i := strings.LastIndex(name, "/"); i != -1 {
+			if parent, ok := pkg.tests[name[:i]]; ok {
+				parent.AddChild(test)
+			}
+		}
+		pkg.tests[name] = test
+		pkg.output[name] = &strings.Builder{}
+		pkg.errors[name] = &strings.Builder{}
+		pkg.suite.Tests = append(pkg.suite.Tests, test)
+	}
+	return test
+}
+
+// finishTest sets the output and message of test
+func (pkg *jsonPackage) finishTest(test *Test) {
+	lines := outputLines(pkg.output[test.Name].String())
+	errLines := outputLines(pkg.errors[test.Name].String())
+	if test.Status == Failed && hasPanic(append(lines, errLines...)) {
+		test.Status = Errored
+	}
+	if Options.FailOnRace && hasDatarace(append(lines, errLines...)) {
+		test.Status = Failed
+	}
+	test.Output = strings.Join(lines, "\n")
+	// Newer go versions mark error lines, use them as failure message.
+	// They are not stderr, so they don't go to ErrOutput.
+	if len(errLines) > 0 {
+		test.Message = strings.Join(errLines, "\n")
+	} else {
+		test.Message = test.Output
+	}
+}
+
+// finish sets the output and message of all tests. Tests that never ended
+// (e.g. a panic in another test) are marked as errored.
+func (pkg *jsonPackage) finish() {
+	for _, test := range pkg.suite.Tests {
+		if test.Status == UnknownStatus {
+			test.Status = Errored
+			test.Time = "0"
+		}
+		pkg.finishTest(test)
+	}
+}
+
+// buildFailed returns the reason ("build failed" or "setup failed") if the
+// package failed to build
+func (pkg *jsonPackage) buildFailed() string {
+	for _, line := range pkg.pkgOutput {
+		if tokens := gtBuildFailedRE.FindStringSubmatch(line); tokens != nil {
+			return tokens[2]
+		}
+	}
+	return ""
+}
+
+// isFrameLine returns true if line is one of the "=== RUN", "--- PASS" ...
+// lines go test uses to delimit tests
+func isFrameLine(line string) bool {
+	return gtStartRE.MatchString(line) || gtEndRE.MatchString(line) ||
+		gtPauseRE.MatchString(line) || gtContRE.MatchString(line)
+}
+
+// outputLines splits test output to lines, dropping test framing lines
+func outputLines(output string) []string {
+	output = strings.TrimSuffix(output, "\n")
+	if output == "" {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if !isFrameLine(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// jsonStatus return matching status for test2json action
+func jsonStatus(action string) Status {
+	switch action {
+	case "pass", "bench":
+		return Passed
+	case "fail":
+		return Failed
+	case "skip":
+		return Skipped
+	}
+	return UnknownStatus
+}
+
+// ParseGotestJSON parses output of "go test -json"
+func ParseGotestJSON(rd io.Reader, suitePrefix string) (Suites, error) {
+	return ParseGotestJSONStream(rd, suitePrefix, nil)
+}
+
+// ParseGotestJSONStream parses output of "go test -json", listener (if not
+// nil) is notified as soon as every test and suite is done
+func ParseGotestJSONStream(rd io.Reader, suitePrefix string, listener TestListener) (Suites, error) {
+	if listener == nil {
+		listener = nopListener{}
+	}
+	suites := []*Suite{}
+	packages := map[string]*jsonPackage{}
+	var order []string
+	buildOutput := map[string][]string{} // import path -> build output
+	textOutput := map[string][]string{}  // package -> build output in older go
+	textPkg := ""                        // package of current "# pkg" block
+
+	scanner := NewLineScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// Older go versions print build errors as is, starting with a "# pkg"
+		// header. Other non-JSON lines (e.g. "go: downloading") are ignored.
+		if !strings.HasPrefix(line, "{") {
+			if pkg := buildHeaderPackage(line); pkg != "" {
+				textPkg = pkg
+			}
+			if textPkg != "" && line != "" {
+				textOutput[textPkg] = append(textOutput[textPkg], scanner.Text())
+			}
+			continue
+		}
+		textPkg = ""
+
+		var event GotestEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return nil, fmt.Errorf("%d: bad JSON - %s", scanner.Line(), err)
+		}
+
+		switch event.Action {
+		case "build-output":
+			output := strings.TrimSuffix(event.Output, "\n")
+			buildOutput[event.ImportPath] = append(buildOutput[event.ImportPath], output)
+			continue
+		case "build-fail":
+			continue
+		}
+
+		pkg, ok := packages[event.Package]
+		if !ok {
+			pkg = newJSONPackage(suitePrefix + event.Package)
+			packages[event.Package] = pkg
+			order = append(order, event.Package)
+		}
+
+		if event.Test == "" {
+			if event.Action == "output" {
+				output := strings.TrimSuffix(event.Output, "\n")
+				pkg.pkgOutput = append(pkg.pkgOutput, output)
+				continue
+			}
+			status := jsonStatus(event.Action)
+			if status == UnknownStatus {
+				continue
+			}
+			// Package is done
+			delete(packages, event.Package)
+			if reason := pkg.buildFailed(); reason != "" {
+				output := textOutput[event.Package]
+				delete(textOutput, event.Package)
+				if event.FailedBuild != "" {
+					output = buildOutput[event.FailedBuild]
+				}
+				suite := newBuildFailedSuite(pkg.suite.Name, reason, strings.Join(output, "\n"))
+				suites = append(suites, suite)
+				listener.SuiteDone(suite)
+				continue
+			}
+			pkg.finish()
+			pkg.suite.Time = fmt.Sprintf("%.3f", event.Elapsed)
+			if len(pkg.suite.Tests) > 0 || status != Skipped {
+				suites = append(suites, pkg.suite)
+				listener.SuiteDone(pkg.suite)
+			}
+			continue
+		}
+
+		test := pkg.test(event.Test)
+		switch event.Action {
+		case "output":
+			if strings.HasPrefix(event.OutputType, "error") {
+				pkg.errors[event.Test].WriteString(event.Output)
+			} else {
+				pkg.output[event.Test].WriteString(event.Output)
+			}
+		case "pass", "fail", "skip", "bench":
+			test.Status = jsonStatus(event.Action)
+			test.Time = fmt.Sprintf("%.2f", event.Elapsed)
+			pkg.finishTest(test)
+			listener.TestDone(pkg.suite, test)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Packages without a summary (e.g. test binary crashed)
+	for _, name := range order {
+		pkg, ok := packages[name]
+		if !ok || len(pkg.suite.Tests) == 0 {
+			continue
+		}
+		pkg.finish()
+		suites = append(suites, pkg.suite)
+		listener.SuiteDone(pkg.suite)
+	}
+
+	return Suites(suites), nil
+}
@@ -14,6 +14,15 @@ var (
 	gtStartRE = regexp.MustCompile(
 		"^=== RUN:?[[:space:]]+([a-zA-Z_][^[:space:]]*)")
 
+	// === PAUSE TestAdd
+	gtPauseRE = regexp.MustCompile(
+		"^=== PAUSE[[:space:]]+([a-zA-Z_][^[:space:]]*)")
+
+	// === CONT  TestAdd
+	// === NAME  TestAdd
+	gtContRE = regexp.MustCompile(
+		"^=== (CONT|NAME)[[:space:]]+([a-zA-Z_][^[:space:]]*)")
+
 	// --- PASS: TestSub (0.00 seconds)
 	// --- FAIL: TestSubFail (0.00 seconds)
 	// --- SKIP: TestSubSkip (0.00 seconds)
@@ -29,7 +38,15 @@ var (
 	// ?       alipay  [no test files]
 	gtNoFilesRE = regexp.MustCompile("^\\?.*\\[no test files\\]$")
 	// FAIL    node/config [build failed]
-	gtBuildFailedRE = regexp.MustCompile(`^FAIL.*\[(build|setup) failed\]$`)
+	// FAIL    node/config [setup failed]
+	gtBuildFailedRE = regexp.MustCompile(
+		`^FAIL[ \t]+([^ \t]+)[ \t]+\[((build|setup) failed)\]$`)
+	// # node/config
+	// # node/config [node/config.test]
+	gtBuildHeaderRE = regexp.MustCompile(`^# ([^ \t]+)( \[([^ \t]+)\.test\])?$`)
+
+	// goroutine 7 [running]:
+	gtGoroutineRE = regexp.MustCompile(`(?m)^goroutine \d+ \[`)
 
 	// exit status - 0
 	gtExitRE = regexp.MustCompile("^exit status -?\\d+")
@@ -76,13 +93,21 @@ func NewLineScanner(r io.Reader) *LineScanner {
 	return ls
 }
 
-// Scan advances to next line.
+// Scan advances to next line. Lines longer than the reader buffer are joined.
 func (ls *LineScanner) Scan() bool {
-	if ls.text, _, ls.err = ls.Reader.ReadLine(); ls.err != nil {
-		if ls.err == io.EOF {
-			ls.err = nil
+	ls.text = ls.text[:0]
+	for {
+		line, isPrefix, err := ls.Reader.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				ls.err = err
+			}
+			return false
+		}
+		ls.text = append(ls.text, line...)
+		if !isPrefix {
+			break
 		}
-		return false
 	}
 	ls.lnum++
 	return true
@@ -114,6 +139,8 @@ const (
 	NoFilesToken
 	BuildFailedToken
 	ExitToken
+	PauseToken
+	ContToken
 	DataToken
 )
 
@@ -141,6 +168,10 @@ func (typ TokenType) String() string {
 		return "Data"
 	case ExitToken:
 		return "Exit"
+	case PauseToken:
+		return "Pause"
+	case ContToken:
+		return "Cont"
 	}
 
 	return "???"
@@ -161,11 +192,13 @@ var gtTypes = []struct {
 	typ TokenType
 	re  *regexp.Regexp
 }{
+	{NoFilesToken, gtNoFilesRE},
+	{BuildFailedToken, gtBuildFailedRE},
 	{StartToken, gtStartRE},
+	{PauseToken, gtPauseRE},
+	{ContToken, gtContRE},
 	{EndToken, gtEndRE},
 	{SuiteToken, gtSuiteRE},
-	{NoFilesToken, gtNoFilesRE},
-	{BuildFailedToken, gtBuildFailedRE},
 	{ExitToken, gtExitRE},
 }
 
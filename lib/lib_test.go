@@ -1,8 +1,18 @@
 package lib
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func loadGotest(filename string, t *testing.T) ([]*Suite, error) {
@@ -52,3 +62,534 @@ func Test_ignoreDatarace(t *testing.T) {
 		t.Fatalf("wrong number of failed %d, should be %d", suite.NumFailed(), numFailed)
 	}
 }
+
+func Test_parseGotestJSON(t *testing.T) {
+	filename := "../_data/in/json-mixed.out"
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("can't open %s - %s", filename, err)
+	}
+	defer file.Close()
+
+	suites, err := ParseGotestJSON(file, "")
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	numSuites := 2
+	if len(suites) != numSuites {
+		t.Fatalf("got %d suites instead of %d", len(suites), numSuites)
+	}
+
+	suite := suites[0]
+	if suite.Name != "example.com/demo/a" {
+		t.Fatalf("bad suite name - %q", suite.Name)
+	}
+	if suite.Len() != 6 || suite.NumFailed() != 3 || suite.NumSkipped() != 1 {
+		t.Fatalf("bad totals: %d tests, %d failed, %d skipped",
+			suite.Len(), suite.NumFailed(), suite.NumSkipped())
+	}
+
+	expectedMessage := "    a_test.go:26: two failed"
+	if msg := suite.Tests[5].Message; msg != expectedMessage {
+		t.Fatalf("bad message - %q (expected %q)", msg, expectedMessage)
+	}
+
+	// TestFail prints to stdout before failing
+	test := suite.Tests[1]
+	expectedMessage = "    a_test.go:14: 1 + 1 != 3"
+	if test.Message != expectedMessage || test.ErrOutput != "" {
+		t.Fatalf("bad message - %q (expected %q)", test.Message, expectedMessage)
+	}
+	expectedOutput := "printed to stdout"
+	if test.Output != expectedOutput {
+		t.Fatalf("bad output - %q (expected %q)", test.Output, expectedOutput)
+	}
+}
+
+func TestGtParserStreaming(t *testing.T) {
+	rd, wr := io.Pipe()
+	parser := NewGtParser(rd, "")
+
+	go func() {
+		fmt.Fprintln(wr, "=== RUN   TestA")
+		fmt.Fprintln(wr, "--- PASS: TestA (0.00s)")
+		fmt.Fprintln(wr, "ok  \tpkg/a\t0.001s")
+	}()
+
+	// Second package was not written yet, we should still get the first one
+	if !parser.Scan() {
+		t.Fatalf("no suite (error: %v)", parser.Err())
+	}
+	if name := parser.Suite().Name; name != "pkg/a" {
+		t.Fatalf("bad suite name - %q", name)
+	}
+
+	go func() {
+		fmt.Fprintln(wr, "=== RUN   TestB")
+		fmt.Fprintln(wr, "--- FAIL: TestB (0.00s)")
+		fmt.Fprintln(wr, "FAIL\tpkg/b\t0.001s")
+		wr.Close()
+	}()
+
+	if !parser.Scan() {
+		t.Fatalf("no suite (error: %v)", parser.Err())
+	}
+	if suite := parser.Suite(); suite.Name != "pkg/b" || suite.NumFailed() != 1 {
+		t.Fatalf("bad suite - %+v", suite)
+	}
+
+	if parser.Scan() {
+		t.Fatalf("extra suite - %+v", parser.Suite())
+	}
+	if err := parser.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTeamCityStream(t *testing.T) {
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := ParseGotestJSONStream(in, "", NewTeamCityStream(out))
+		out.Close()
+		done <- err
+	}()
+
+	fmt.Fprintln(inWriter, `{"Action":"run","Package":"pkg/a","Test":"TestA"}`)
+	fmt.Fprintln(inWriter, `{"Action":"pass","Package":"pkg/a","Test":"TestA","Elapsed":0.01}`)
+
+	// TestA is written before the package is done
+	rd := bufio.NewReader(outReader)
+	for _, expected := range []string{"testSuiteStarted", "testStarted", "testFinished"} {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			t.Fatalf("can't read %s - %s", expected, err)
+		}
+		if !strings.HasPrefix(line, "##teamcity["+expected+" ") || !strings.Contains(line, "flowId='pkg/a'") {
+			t.Fatalf("bad message - %q (expected %s)", line, expected)
+		}
+	}
+
+	fmt.Fprintln(inWriter, `{"Action":"pass","Package":"pkg/a","Elapsed":0.02}`)
+	inWriter.Close()
+	rest, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "##teamcity[testSuiteFinished name='pkg/a' flowId='pkg/a']\n"; string(rest) != expected {
+		t.Fatalf("bad output - %q (expected %q)", rest, expected)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_parseXUnit(t *testing.T) {
+	filename := "../_data/nosetests.xml"
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("can't open %s - %s", filename, err)
+	}
+	defer file.Close()
+
+	suites, err := ParseXUnit(file, "")
+	if err != nil {
+		t.Fatalf("error loading %s - %s", filename, err)
+	}
+
+	if len(suites) != 1 {
+		t.Fatalf("got %d suites instead of 1", len(suites))
+	}
+	suite := suites[0]
+	if suite.Name != "nosetests" || suite.Len() != 3 || suite.NumFailed() != 1 {
+		t.Fatalf("bad suite - %+v", suite)
+	}
+
+	expectedMessage := "AssertionError: bad sub"
+	if msg := suite.Tests[2].Message; !strings.HasSuffix(msg, expectedMessage) {
+		t.Fatalf("bad message - %q (expected suffix %q)", msg, expectedMessage)
+	}
+}
+
+func Test_parseXUnitClassName(t *testing.T) {
+	xmlData := `<testsuite name="s" time="1&quot;&lt;">
+  <testcase classname="A" name="test_x" time="0.1&quot;x"/>
+  <testcase classname="B" name="test_x" time="0.25"/>
+  <testcase classname="s" name="test_y" time="0.5"/>
+</testsuite>`
+	suites, err := ParseXUnit(strings.NewReader(xmlData), "")
+	if err != nil {
+		t.Fatalf("error parsing - %s", err)
+	}
+	if suites[0].Time != "0" {
+		t.Fatalf("bad suite time - %q", suites[0].Time)
+	}
+
+	merged := MergeSuites(suites, suites)
+	suite := merged[0]
+	if suite.Len() != 3 {
+		t.Fatalf("got %d tests instead of 3", suite.Len())
+	}
+	expected := []struct{ class, time string }{{"A", "0"}, {"B", "0.25"}, {"s", "0.5"}}
+	for i, test := range suite.Tests {
+		if class := test.ClassName(suite); class != expected[i].class || test.Time != expected[i].time {
+			t.Fatalf("%d: bad class or time - %q, %q", i, class, test.Time)
+		}
+	}
+}
+
+func Test_timeSpan(t *testing.T) {
+	cases := map[string]string{
+		"":        "00:00:00.0000000",
+		"0.005":   "00:00:00.0050000",
+		"1.234":   "00:00:01.2340000",
+		"3725.5":  "01:02:05.5000000",
+		"invalid": "00:00:00.0000000",
+	}
+	for duration, expected := range cases {
+		if out := timeSpan(duration); out != expected {
+			t.Fatalf("timeSpan(%q): got %q, expected %q", duration, out, expected)
+		}
+	}
+}
+
+func Test_durations(t *testing.T) {
+	cases := []struct {
+		duration string
+		seconds  string
+		millis   int64
+		ms       string
+	}{
+		{"", "0", 0, "0"},
+		{"0.0125", "0.0125", 13, "12.5"},
+		{"1.5", "1.5", 1500, "1500"},
+		{"-0.01", "0", 0, "0"},
+		{"invalid", "0", 0, "0"},
+	}
+	for _, tc := range cases {
+		if out := seconds(tc.duration); out != tc.seconds {
+			t.Fatalf("seconds(%q): got %q, expected %q", tc.duration, out, tc.seconds)
+		}
+		if out := millis(tc.duration); out != tc.millis {
+			t.Fatalf("millis(%q): got %d, expected %d", tc.duration, out, tc.millis)
+		}
+		if out := durationMs(tc.duration); out != tc.ms {
+			t.Fatalf("durationMs(%q): got %q, expected %q", tc.duration, out, tc.ms)
+		}
+	}
+}
+
+func Test_writeGitHubAnnotations(t *testing.T) {
+	suites := Suites{
+		{Name: "example.com/demo/a", Tests: []*Test{
+			{Name: "TestOK", Status: Passed},
+			{Name: "TestFail", Status: Failed, Message: "    a_test.go:14: 50% done,\n    missing: 1"},
+			{Name: "TestNoFile", Status: Errored},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGitHubAnnotations(suites, &buf, "example.com/demo"); err != nil {
+		t.Fatalf("error writing annotations - %s", err)
+	}
+	expected := "::error file=a/a_test.go,line=14,title=TestFail failed::a_test.go:14: 50%25 done,%0A    missing: 1\n" +
+		"::error title=TestNoFile errored::TestNoFile errored\n"
+	if out := buf.String(); out != expected {
+		t.Fatalf("bad annotations:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func Test_writeSonar(t *testing.T) {
+	root, err := ioutil.TempDir("", "go2xunit-sonar")
+	if err != nil {
+		t.Fatalf("can't create temp dir - %s", err)
+	}
+	defer os.RemoveAll(root)
+	if err := os.Mkdir(filepath.Join(root, "a"), 0755); err != nil {
+		t.Fatalf("can't create package dir - %s", err)
+	}
+	code := "package a\n\nfunc TestAdd(t *testing.T) {\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "a", "add_test.go"), []byte(code), 0644); err != nil {
+		t.Fatalf("can't write test file - %s", err)
+	}
+
+	suites := Suites{
+		{Name: "example.com/m/a", Tests: []*Test{
+			{Name: "TestAdd", Time: "0.25", Status: Passed},
+			{Name: "TestAdd/sub", Time: "0.01", Status: Failed, Message: "    add_test.go:9: bad"},
+			{Name: "TestSub", Time: "0.5", Status: Failed, Message: "    sub_test.go:7: oops"},
+			{Name: "TestUnknown", Status: Passed},
+		}},
+	}
+
+	var buf bytes.Buffer
+	locator := &TestLocator{Module: "example.com/m", Root: root}
+	if err := WriteSonar(suites, &buf, locator); err != nil {
+		t.Fatalf("error writing sonar - %s", err)
+	}
+	out := buf.String()
+	expected := []string{
+		`<file path="a/add_test.go">`,
+		`<testCase name="TestAdd" duration="250"/>`,
+		`<testCase name="TestAdd/sub" duration="10">`,
+		`<failure message="add_test.go:9: bad">`,
+		`<file path="a/sub_test.go">`,
+		`<testCase name="TestSub" duration="500">`,
+	}
+	for _, text := range expected {
+		if !strings.Contains(out, text) {
+			t.Fatalf("%q not found in:\n%s", text, out)
+		}
+	}
+	if strings.Contains(out, "TestUnknown") {
+		t.Fatalf("test without a file in output:\n%s", out)
+	}
+
+	var warnings bytes.Buffer
+	suites[0].Tests = suites[0].Tests[3:]
+	locator = &TestLocator{Root: root, Warnings: &warnings}
+	if err := WriteSonar(suites, &buf, locator); err != nil {
+		t.Fatalf("error writing sonar - %s", err)
+	}
+	if !strings.Contains(warnings.String(), "no test files found") {
+		t.Fatalf("no warning - %q", warnings.String())
+	}
+}
+
+func Test_writeJSON(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Time: "0.5", Tests: []*Test{
+			{Name: "TestPass", Time: "0.2", Status: Passed},
+			{Name: "TestPanic", Time: "0", Status: Errored, Message: "panic: oops"},
+		}},
+	}
+	start := time.Date(2015, 6, 5, 18, 34, 41, 0, time.UTC)
+
+	var buf bytes.Buffer
+	if err := WriteJSON(suites, &buf, start); err != nil {
+		t.Fatalf("error writing JSON - %s", err)
+	}
+	var report JSONReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("bad JSON - %s\n%s", err, buf.String())
+	}
+	if report.Version != JSONVersion || report.Totals.Tests != 2 || report.Totals.Errored != 1 {
+		t.Fatalf("bad report header: %+v", report)
+	}
+	if status := report.Suites[0].Tests[1].Status; status != Errored {
+		t.Fatalf("bad status - %s", status)
+	}
+	if !strings.Contains(buf.String(), `"status": "errored"`) {
+		t.Fatalf("status not encoded by name:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteCTRF(suites, &buf, start); err != nil {
+		t.Fatalf("error writing CTRF - %s", err)
+	}
+	var ctrf ctrfReport
+	if err := json.Unmarshal(buf.Bytes(), &ctrf); err != nil {
+		t.Fatalf("bad CTRF - %s\n%s", err, buf.String())
+	}
+	summary := ctrf.Results.Summary
+	if summary.Tests != 2 || summary.Failed != 1 || summary.Stop-summary.Start != 500 {
+		t.Fatalf("bad CTRF summary: %+v", summary)
+	}
+	if test := ctrf.Results.Tests[1]; test.Status != "failed" || test.RawStatus != "errored" {
+		t.Fatalf("bad CTRF test: %+v", test)
+	}
+}
+
+func Test_writeAllure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2xunit-allure")
+	if err != nil {
+		t.Fatalf("can't create temp dir - %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	suites := Suites{
+		{Name: "example.com/m/a", Tests: []*Test{
+			{Name: "TestPass", Time: "0.2", Status: Passed},
+			{Name: "TestA/b", Time: "0", Status: Errored, Message: "panic: oops", Output: "panic: oops"},
+		}},
+	}
+	if err := WriteAllure(suites, dir, time.Now()); err != nil {
+		t.Fatalf("error writing Allure results - %s", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*-result.json"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 result files, got %d", len(files))
+	}
+	attachments, _ := filepath.Glob(filepath.Join(dir, "*-attachment.txt"))
+	if len(attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(attachments))
+	}
+
+	found := false
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("can't read %s - %s", file, err)
+		}
+		var result allureResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("bad JSON in %s - %s", file, err)
+		}
+		if result.Name != "TestA/b" {
+			continue
+		}
+		found = true
+		if result.Status != "broken" || result.StatusDetails.Message != "panic: oops" {
+			t.Fatalf("bad result: %+v", result)
+		}
+		if len(result.Attachments) != 1 || filepath.Join(dir, result.Attachments[0].Source) != attachments[0] {
+			t.Fatalf("bad attachments: %+v", result.Attachments)
+		}
+	}
+	if !found {
+		t.Fatalf("TestA/b result not found")
+	}
+}
+
+func TestRegisterWriter(t *testing.T) {
+	count := WriterFunc(func(suites Suites, out io.Writer, info RunInfo) error {
+		_, err := fmt.Fprintf(out, "%d suites", len(suites))
+		return err
+	})
+	RegisterWriter("count", count)
+	defer delete(writers, "count")
+
+	writer, ok := GetWriter("count")
+	if !ok {
+		t.Fatalf("count writer not found")
+	}
+	var buf bytes.Buffer
+	if err := writer.Write(Suites{{Name: "a"}, {Name: "b"}}, &buf, RunInfo{}); err != nil {
+		t.Fatalf("error writing - %s", err)
+	}
+	if out := buf.String(); out != "2 suites" {
+		t.Fatalf("bad output - %q", out)
+	}
+
+	found := false
+	for _, name := range WriterNames() {
+		found = found || name == "count"
+	}
+	if !found {
+		t.Fatalf("count not in %v", WriterNames())
+	}
+}
+
+func TestWritersNoSuites(t *testing.T) {
+	for _, name := range WriterNames() {
+		writer, _ := GetWriter(name)
+		var buf bytes.Buffer
+		if err := writer.Write(Suites{}, &buf, RunInfo{Start: time.Now()}); err != nil {
+			t.Fatalf("%s: error writing - %s", name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTemplate(Suites{}, &buf, "{{.Assembly}}{{.Len}}", time.Now()); err != nil {
+		t.Fatalf("error writing template - %s", err)
+	}
+	if out := buf.String(); out != "0" {
+		t.Fatalf("bad output - %q", out)
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Tests: []*Test{
+			{Name: "TestA", Time: "0.25", Status: Failed, Message: "got ]]> expected 1"},
+		}},
+	}
+	tmpl := `{{range .Suites}}{{range .Tests}}{{json .Name}} {{statusName .Status}} ` +
+		`{{durationMs .Time}} {{.Message | truncate 6}} {{cdata .Message}}{{end}}{{end}}`
+
+	var buf bytes.Buffer
+	if err := WriteTemplate(suites, &buf, tmpl, time.Now()); err != nil {
+		t.Fatalf("error writing template - %s", err)
+	}
+	expected := `"TestA" Failed 250 got ]]... <![CDATA[got ]]]]><![CDATA[> expected 1]]>`
+	if out := buf.String(); out != expected {
+		t.Fatalf("bad output:\n%s\nexpected:\n%s", out, expected)
+	}
+
+	// Nothing is written if the template fails
+	buf.Reset()
+	if err := WriteTemplate(suites, &buf, "partial {{.NoSuchField}}", time.Now()); err == nil {
+		t.Fatalf("no error on bad template")
+	}
+	if buf.Len() > 0 {
+		t.Fatalf("output written on error - %q", buf.String())
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(data []byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+// checkXML returns an error if data is not well formed XML
+func checkXML(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	suites := Suites{
+		{Name: "a", Tests: []*Test{
+			{Name: "TestA\x1b", Time: "0.25", Status: Failed, Message: "got ]]>\x00 \x1b[31mred\x1b[0m"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(suites, &buf, XUnitTemplate, time.Now()); err != nil {
+		t.Fatalf("error writing XML - %s", err)
+	}
+	if out := buf.String(); strings.ContainsAny(out, "\x00\x1b") {
+		t.Fatalf("illegal characters in output:\n%q", out)
+	}
+	if _, err := ParseXUnit(&buf, ""); err != nil {
+		t.Fatalf("bad XML - %s", err)
+	}
+
+	// Times from merged XML files are not numbers
+	suites = Suites{
+		{Name: "s", Time: `1"<`, Tests: []*Test{
+			{Name: "t", Time: `0.1"x`, Status: Passed},
+		}},
+	}
+	templates := []string{
+		XUnitTemplate, XMLMultiTemplate, XUnitNestedTemplate, XUnitNetTemplate,
+		XUnitNet2Template, NUnitTemplate, TRXTemplate,
+	}
+	for _, tmpl := range templates {
+		buf.Reset()
+		if err := WriteXML(suites, &buf, tmpl, time.Now()); err != nil {
+			t.Fatalf("error writing XML - %s", err)
+		}
+		if err := checkXML(buf.Bytes()); err != nil {
+			t.Fatalf("bad XML - %s\n%s", err, buf.String())
+		}
+	}
+
+	if err := WriteXML(suites, &buf, "{{.NoSuchField}}", time.Now()); err == nil {
+		t.Fatalf("no error on bad template")
+	}
+	if err := WriteXML(suites, failWriter{}, XUnitTemplate, time.Now()); err == nil {
+		t.Fatalf("no error on write failure")
+	}
+}
@@ -0,0 +1,113 @@
+package lib
+
+// Markdown summary output
+import (
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// maxSlowest is the number of tests in the slowest tests table
+const maxSlowest = 10
+
+// MarkdownTemplate is the template for a markdown summary (e.g. for GitHub's
+// $GITHUB_STEP_SUMMARY)
+const MarkdownTemplate = `# Test Results
+
+{{if or .NumFailed .NumErrored}}:x:{{else}}:white_check_mark:{{end}} **{{.Len}} tests**: {{.NumPassed}} passed, {{.NumFailed}} failed, {{.NumErrored}} errored, {{.NumSkipped}} skipped in {{.Time}}s
+
+| Suite | Passed | Failed | Errored | Skipped | Time (s) |
+| --- | ---: | ---: | ---: | ---: | ---: |
+{{range .Suites}}| {{.Name | cell}} | {{.NumPassed}} | {{.NumFailed}} | {{.NumErrored}} | {{.NumSkipped}} | {{.Time}} |
+{{end}}{{if .Failures}}
+## Failures
+{{range .Failures}}
+<details>
+<summary>{{.Status}}: {{.Suite | html}} {{.Name | html}}</summary>
+
+{{code .Message}}
+
+</details>
+{{end}}{{end}}{{if .Slowest}}
+## Slowest Tests
+
+| Test | Suite | Time (s) |
+| --- | --- | ---: |
+{{range .Slowest}}| {{.Name | cell}} | {{.Suite | cell}} | {{.Time}} |
+{{end}}{{end}}`
+
+// mdTest is a test with the name of its suite
+type mdTest struct {
+	*Test
+	Suite string
+}
+
+// mdReport is passed to the markdown template
+type mdReport struct {
+	TestResults
+	Failures []mdTest
+	Slowest  []mdTest
+}
+
+// mdCell escapes text for a markdown table cell
+func mdCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// mdCode returns text as a fenced code block, the fence is longer than any
+// backtick run in text
+func mdCode(text string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + text + "\n" + fence
+}
+
+// newMDReport returns markdown template data for suites
+func newMDReport(suites []*Suite) *mdReport {
+	report := &mdReport{TestResults: newTestResults(suites, time.Time{})}
+	var timed []mdTest
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			if test.IsParent() {
+				continue
+			}
+			mt := mdTest{test, suite.Name}
+			if test.Status == Failed || test.Status == Errored {
+				report.Failures = append(report.Failures, mt)
+			}
+			if secs, _ := strconv.ParseFloat(test.Time, 64); secs > 0 {
+				timed = append(timed, mt)
+			}
+		}
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool {
+		ti, _ := strconv.ParseFloat(timed[i].Time, 64)
+		tj, _ := strconv.ParseFloat(timed[j].Time, 64)
+		return ti > tj
+	})
+	if len(timed) > maxSlowest {
+		timed = timed[:maxSlowest]
+	}
+	report.Slowest = timed
+	return report
+}
+
+// WriteMarkdown writes a markdown summary of suites to out: totals per suite,
+// failing tests and the slowest tests
+func WriteMarkdown(suites []*Suite, out io.Writer) error {
+	t, err := template.New("markdown").Funcs(template.FuncMap{
+		"cell": mdCell,
+		"code": mdCode,
+	}).Parse(MarkdownTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(out, newMDReport(suites))
+}
@@ -1,6 +1,10 @@
 package lib
 
-import "io"
+import (
+	"fmt"
+	"io"
+	"strings"
+)
 
 // Parser generates suites
 type Parser interface {
@@ -9,33 +13,406 @@ type Parser interface {
 	Err() error
 }
 
-// GtParser is a gotest output parser
+// TestListener is notified by streaming parsers (see ParseGotestJSONStream)
+type TestListener interface {
+	// TestDone is called when test in suite ended
+	TestDone(suite *Suite, test *Test)
+	// SuiteDone is called when suite ended, tests that didn't end (e.g.
+	// after a panic) were not passed to TestDone
+	SuiteDone(suite *Suite)
+}
+
+type nopListener struct{}
+
+func (nopListener) TestDone(suite *Suite, test *Test) {}
+func (nopListener) SuiteDone(suite *Suite)            {}
+
+// GtParser is a gotest output parser, it emits a suite as soon as it's done
 type GtParser struct {
-	suite *Suite
-	tests map[string]*Test
-	lex   Lexer
+	lex    Lexer
+	prefix string
+
+	ready     []*Suite // suites done but not returned yet
+	suite     *Suite   // current suite (returned by Suite)
+	numSuites int      // number of suites emitted so far
+	done      bool
+	err       error
+
+	curSuite   *Suite
+	curTest    *Test          // test getting output (last RUN or CONT)
+	running    []*Test        // tests started but not ended yet
+	inSuite    map[*Test]bool // tests already added to curSuite
+	out        []string
+	suiteStack SuiteStack
+
+	buildPkg    string              // package of the current "# pkg" block
+	buildOutput map[string][]string // package -> compiler output
 }
 
 // Scan scans for the next suite
 func (gtp *GtParser) Scan() bool {
+	for len(gtp.ready) == 0 && !gtp.done {
+		if !gtp.lex.Scan() {
+			gtp.done = true
+			if gtp.err = gtp.lex.Err(); gtp.err != nil {
+				break
+			}
+			gtp.finish()
+			break
+		}
+		if gtp.err = gtp.handle(gtp.lex.Token()); gtp.err != nil {
+			gtp.done = true
+		}
+	}
+
+	if gtp.err != nil || len(gtp.ready) == 0 {
+		gtp.suite = nil
+		return false
+	}
+
+	gtp.suite, gtp.ready = gtp.ready[0], gtp.ready[1:]
 	return true
 }
 
 // Suite is the current suite
 func (gtp *GtParser) Suite() *Suite {
-	return nil
+	return gtp.suite
 }
 
 // Err is the current error
 func (gtp *GtParser) Err() error {
+	return gtp.err
+}
+
+// emit marks suite as done
+func (gtp *GtParser) emit(suite *Suite) {
+	gtp.ready = append(gtp.ready, suite)
+	gtp.numSuites++
+}
+
+// findRunning return the running test called name (nil if not found)
+func (gtp *GtParser) findRunning(name string) *Test {
+	for _, test := range gtp.running {
+		if test.Name == name {
+			return test
+		}
+	}
+	return nil
+}
+
+// stopRunning removes test from running tests
+func (gtp *GtParser) stopRunning(test *Test) {
+	for i, t := range gtp.running {
+		if t == test {
+			gtp.running = append(gtp.running[:i], gtp.running[i+1:]...)
+			return
+		}
+	}
+}
+
+// findParent return the running parent of a sub test (nil if not found)
+func (gtp *GtParser) findParent(name string) *Test {
+	i := strings.LastIndex(name, "/")
+	if i == -1 {
+		return nil
+	}
+	return gtp.findRunning(name[:i])
+}
+
+// addTest adds test to the current suite, unless it's already there
+func (gtp *GtParser) addTest(test *Test) {
+	if gtp.inSuite[test] {
+		return
+	}
+	gtp.curSuite.Tests = append(gtp.curSuite.Tests, test)
+	gtp.inSuite[test] = true
+}
+
+// handlePanic handles a test that ended with a panic
+func (gtp *GtParser) handlePanic(test *Test) {
+	test.Status = Errored
+	test.Time = "0"
+	gtp.addTest(test)
+	gtp.stopRunning(test)
+	if test == gtp.curTest {
+		gtp.curTest = nil
+	}
+}
+
+// handleRunning marks all tests that didn't end as failed
+func (gtp *GtParser) handleRunning() {
+	if gtp.curTest != nil {
+		gtp.handlePanic(gtp.curTest)
+	}
+	for len(gtp.running) > 0 {
+		gtp.handlePanic(gtp.running[0])
+	}
+}
+
+// appendMessage appends output to test
+func (gtp *GtParser) appendMessage(test *Test) {
+	test.appendOutput(gtp.out)
+	if len(gtp.out) > 0 && !test.IsParent() {
+		if test.Status == Failed && hasPanic(gtp.out) {
+			test.Status = Errored
+		}
+		message := strings.Join(gtp.out, "\n")
+		if test.Message == "" {
+			test.Message = message
+		} else {
+			test.Message += "\n" + message
+		}
+		test.AppendedErrorOutput = gcTestErrorRE.MatchString(message)
+	}
+	gtp.out = []string{}
+}
+
+// appendError appends output to the last test
+func (gtp *GtParser) appendError() {
+	suite := gtp.curSuite
+	if suite != nil && len(suite.Tests) > 0 {
+		gtp.appendMessage(suite.Tests[len(suite.Tests)-1])
+	}
+	gtp.out = []string{}
+}
+
+// handle handles a single token
+func (gtp *GtParser) handle(tok *Token) error {
+	switch tok.Type {
+	case NoFilesToken:
+		// TODO: Only outside a suite/test, report as empty suite?
+		return nil
+	case BuildFailedToken:
+		gtp.handleBuildFailed(tok)
+		return nil
+	}
+
+	if tok.Type != DataToken {
+		gtp.buildPkg = ""
+	}
+
+	if gtp.curSuite == nil {
+		gtp.curSuite = &Suite{}
+	}
+
+	switch tok.Type {
+	case StartToken:
+		gtp.handleStart(tok)
+	case PauseToken:
+		gtp.handlePause(tok)
+	case ContToken:
+		gtp.handleCont(tok)
+	case EndToken:
+		return gtp.handleEnd(tok)
+	case SuiteToken:
+		gtp.handleSuite(tok)
+	case ExitToken:
+		// Nothing to do
+	default:
+		if tok.Data == "FAIL" || tok.Data == "PASS" {
+			gtp.buildPkg = ""
+			return nil
+		}
+		if gtp.handleBuildOutput(tok) {
+			return nil
+		}
+		gtp.out = append(gtp.out, tok.Data)
+	}
+
+	return nil
+}
+
+func (gtp *GtParser) handleStart(tok *Token) {
+	name := gtStartRE.FindStringSubmatch(tok.Data)[1]
+	parent := gtp.findParent(name)
+	if parent != nil {
+		if parent.IsParent() {
+			parent.AppendedErrorOutput = true
+		}
+		// Parent is added to the suite when the first subtest starts
+		gtp.addTest(parent)
+	} else if curTest := gtp.curTest; curTest != nil {
+		// This occurs when the last test ended with a panic, or in testify
+		// suites
+		if gtp.suiteStack.count == 0 {
+			gtp.suiteStack.Push(gtp.curSuite)
+			gtp.curSuite = &Suite{Name: curTest.Name}
+			gtp.stopRunning(curTest)
+		} else {
+			gtp.handlePanic(curTest)
+		}
+	}
+	if curTest := gtp.curTest; curTest != nil && gtp.findRunning(curTest.Name) == curTest {
+		// Output since the last RUN or CONT belongs to the current test
+		gtp.appendMessage(curTest)
+	} else {
+		gtp.appendError()
+	}
+	gtp.curTest = &Test{Name: name}
+	gtp.running = append(gtp.running, gtp.curTest)
+	if parent != nil {
+		parent.AddChild(gtp.curTest)
+		gtp.addTest(gtp.curTest)
+	}
+}
+
+// handlePause handles "=== PAUSE", the test will continue after all serial
+// tests are done
+func (gtp *GtParser) handlePause(tok *Token) {
+	name := gtPauseRE.FindStringSubmatch(tok.Data)[1]
+	test := gtp.findRunning(name)
+	if test == nil {
+		return
+	}
+	gtp.appendMessage(test)
+	if test == gtp.curTest {
+		gtp.curTest = nil
+	}
+}
+
+// handleCont handles "=== CONT" (and "=== NAME"), output from now on belongs
+// to the named test
+func (gtp *GtParser) handleCont(tok *Token) {
+	name := gtContRE.FindStringSubmatch(tok.Data)[2]
+	if gtp.curTest != nil {
+		gtp.appendMessage(gtp.curTest)
+	} else {
+		gtp.appendError()
+	}
+	gtp.curTest = gtp.findRunning(name)
+}
+
+func (gtp *GtParser) handleEnd(tok *Token) error {
+	tokens := gtEndRE.FindStringSubmatch(tok.Data)
+	test := gtp.findRunning(tokens[2])
+	if test == nil {
+		if gtp.curTest == nil && gtp.suiteStack.count > 0 {
+			prevSuite := gtp.suiteStack.Pop()
+			gtp.emit(gtp.curSuite)
+			gtp.curSuite = prevSuite
+			return nil
+		}
+		return fmt.Errorf("%d: orphan end test", tok.Line)
+	}
+
+	if gtp.curTest != nil && gtp.curTest != test {
+		// Output of another test (e.g. parent ending after sub tests)
+		gtp.appendMessage(gtp.curTest)
+	}
+
+	test.Status = Token2Status(tokens[1])
+	if test.Status == UnknownStatus {
+		return fmt.Errorf("%d: unknown status - %s", tok.Line, tokens[1])
+	}
+	if Options.FailOnRace && hasDatarace(gtp.out) {
+		test.Status = Failed
+	}
+	test.Time = tokens[3]
+
+	if len(gtp.out) > 0 {
+		message := strings.Join(gtp.out, "\n")
+		prevTest, err := getPreviousFailTest(gtp.curSuite, test)
+		// Output after the end of a test (old go versions) belongs to the
+		// previous failing test
+		target := test
+		if err == nil && !prevTest.AppendedErrorOutput && test != gtp.curTest {
+			target = prevTest
+		}
+		target.appendOutput(gtp.out)
+		if !target.IsParent() {
+			if target.Message != "" {
+				target.Message += "\n"
+			}
+			target.Message += message
+			target.AppendedErrorOutput = gcTestErrorRE.MatchString(message)
+		}
+	}
+
+	gtp.addTest(test)
+	gtp.stopRunning(test)
+	gtp.curTest = nil
+	gtp.out = []string{}
 	return nil
 }
 
+func (gtp *GtParser) handleSuite(tok *Token) {
+	tokens := gtSuiteRE.FindStringSubmatch(tok.Data)
+	// This occurs when the last test ended with a panic.
+	gtp.handleRunning()
+	gtp.appendError()
+	gtp.curSuite.Name = gtp.prefix + tokens[2]
+	gtp.curSuite.Time = tokens[3]
+	gtp.emit(gtp.curSuite)
+	gtp.curSuite = nil
+	gtp.inSuite = make(map[*Test]bool)
+}
+
+// handleBuildOutput collects compiler output, which starts with "# pkg" and
+// may be printed long before the package "[build failed]" line. It returns
+// true if tok is compiler output.
+func (gtp *GtParser) handleBuildOutput(tok *Token) bool {
+	if gtp.curTest == nil && len(gtp.running) == 0 {
+		if pkg := buildHeaderPackage(tok.Data); pkg != "" {
+			gtp.buildPkg = pkg
+			gtp.buildOutput[gtp.buildPkg] = append(gtp.buildOutput[gtp.buildPkg], tok.Data)
+			return true
+		}
+	}
+	if gtp.buildPkg == "" {
+		return false
+	}
+	gtp.buildOutput[gtp.buildPkg] = append(gtp.buildOutput[gtp.buildPkg], tok.Data)
+	return true
+}
+
+// handleBuildFailed emits a failing suite for a package that didn't build, the
+// output is the package compiler output or the output before the FAIL line
+func (gtp *GtParser) handleBuildFailed(tok *Token) {
+	tokens := gtBuildFailedRE.FindStringSubmatch(tok.Data)
+	gtp.buildPkg = ""
+	var output string
+	if lines, ok := gtp.buildOutput[tokens[1]]; ok {
+		output = strings.Join(lines, "\n")
+		delete(gtp.buildOutput, tokens[1])
+	} else {
+		output = strings.Join(gtp.out, "\n")
+		gtp.out = []string{}
+	}
+	if gtp.curSuite != nil && len(gtp.curSuite.Tests) == 0 {
+		gtp.curSuite = nil
+	}
+	gtp.emit(newBuildFailedSuite(gtp.prefix+tokens[1], tokens[2], output))
+}
+
+// finish is called at end of input
+func (gtp *GtParser) finish() {
+	if gtp.curTest != nil || len(gtp.running) > 0 {
+		// This occurs when the last test fatal'd outside of the `go test` runner.
+		gtp.handleRunning()
+	}
+
+	// If there were no suites found, but everything else went OK, return a
+	// generic suite.
+	if gtp.numSuites == 0 && gtp.curSuite != nil {
+		if gtp.curSuite.Name == "" {
+			gtp.curSuite.Name = gtp.prefix
+		}
+		// Catch any post-failure messages from the last test
+		gtp.appendError()
+	}
+
+	if gtp.curSuite != nil && len(gtp.curSuite.Tests) > 0 {
+		gtp.emit(gtp.curSuite)
+	}
+	gtp.curSuite = nil
+}
+
 // NewGtParser return a new gotest parser
-func NewGtParser(in io.Reader) Parser {
+func NewGtParser(in io.Reader, suitePrefix string) Parser {
 	return &GtParser{
-		suite: nil,
-		tests: make(map[string]*Test),
-		lex:   NewGotestLexer(in),
+		lex:         NewGotestLexer(in),
+		prefix:      suitePrefix,
+		inSuite:     make(map[*Test]bool),
+		buildOutput: make(map[string][]string),
 	}
 }
@@ -0,0 +1,154 @@
+package lib
+
+// SonarQube generic test execution output
+// see https://docs.sonarsource.com/sonarqube/latest/analyzing-source-code/test-coverage/generic-test-data/
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// SonarTemplate is XML template for SonarQube generic test execution
+const SonarTemplate = `
+<testExecutions version="1">
+{{range $file := .Files}}  <file path="{{$file.Path | escape}}">
+{{range $test := $file.Tests}}    <testCase name="{{$test.Name | escape}}" duration="{{millis $test.Time}}"{{if eq $test.Status $.Passed}}/>
+{{else}}>
+{{if eq $test.Status $.Skipped}}      <skipped message="{{firstLine $test.Message | escape}}">{{cdata $test.Message}}</skipped>
+{{else if eq $test.Status $.Errored}}      <error message="{{firstLine $test.Message | escape}}">{{cdata $test.Message}}</error>
+{{else}}      <failure message="{{firstLine $test.Message | escape}}">{{cdata $test.Message}}</failure>
+{{end}}    </testCase>
+{{end}}{{end}}  </file>
+{{end}}</testExecutions>
+`
+
+// func TestAdd(t *testing.T) {
+var testFuncRE = regexp.MustCompile(`(?m)^func\s+((Test|Benchmark|Example|Fuzz)\w*)\s*\(`)
+
+// TestLocator finds the _test.go file of tests by scanning package directories
+// for test functions, it falls back to file:line in the test message
+type TestLocator struct {
+	Module   string    // Go module path
+	Root     string    // Module root directory
+	Warnings io.Writer // Warnings are written here (optional)
+
+	funcs map[string]map[string]string // package -> test function -> file
+}
+
+// pkgDir returns package directory relative to the module root ("" if the
+// package is not in the module)
+func (l *TestLocator) pkgDir(pkg string) string {
+	switch {
+	case l.Module == "":
+		return ""
+	case pkg == l.Module:
+		return "."
+	case strings.HasPrefix(pkg, l.Module+"/"):
+		return strings.TrimPrefix(pkg, l.Module+"/")
+	}
+	return ""
+}
+
+// testFuncs returns a map of test function to the file it's defined in
+func (l *TestLocator) testFuncs(pkg string) map[string]string {
+	if funcs, ok := l.funcs[pkg]; ok {
+		return funcs
+	}
+
+	funcs := make(map[string]string)
+	if l.funcs == nil {
+		l.funcs = make(map[string]map[string]string)
+	}
+	l.funcs[pkg] = funcs
+
+	dir := l.pkgDir(pkg)
+	if dir == "" {
+		return funcs
+	}
+	files, _ := filepath.Glob(filepath.Join(l.Root, dir, "*_test.go"))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, match := range testFuncRE.FindAllStringSubmatch(string(data), -1) {
+			funcs[match[1]] = path.Join(filepath.ToSlash(dir), filepath.Base(file))
+		}
+	}
+	return funcs
+}
+
+// File returns the test file of test in suite, relative to the module root
+// ("" if not found)
+func (l *TestLocator) File(suite *Suite, test *Test) string {
+	name := strings.SplitN(test.Name, "/", 2)[0] // sub tests are in the parent file
+	if file := l.testFuncs(suite.Name)[name]; file != "" {
+		return file
+	}
+	if file, _ := fileLine(test.Message); file != "" {
+		return annotationFile(file, suite.Name, l.Module, l.Root)
+	}
+	return ""
+}
+
+// sonarFile is a test file with its tests
+type sonarFile struct {
+	Path  string
+	Tests []*Test
+}
+
+// sonarReport is passed to the sonar template
+type sonarReport struct {
+	Files []*sonarFile
+
+	Skipped Status
+	Passed  Status
+	Errored Status
+}
+
+// WriteSonar writes suites to out in SonarQube generic test execution format.
+// Tests are grouped by the file found by locator, tests without a file are
+// not written since SonarQube rejects unknown files. A warning is written to
+// locator.Warnings if no test file was found.
+func WriteSonar(suites []*Suite, out io.Writer, locator *TestLocator) error {
+	report := &sonarReport{Skipped: Skipped, Passed: Passed, Errored: Errored}
+	files := make(map[string]*sonarFile)
+	numTests := 0
+	for _, suite := range suites {
+		for _, test := range suite.Tests {
+			numTests++
+			name := locator.File(suite, test)
+			if name == "" {
+				continue
+			}
+			file, ok := files[name]
+			if !ok {
+				file = &sonarFile{Path: name}
+				files[name] = file
+				report.Files = append(report.Files, file)
+			}
+			file.Tests = append(file.Tests, test)
+		}
+	}
+
+	if len(report.Files) == 0 && numTests > 0 && locator.Warnings != nil {
+		fmt.Fprintf(locator.Warnings, "warning: no test files found for sonar report (module %q)\n", locator.Module)
+	}
+
+	t, err := template.New("sonar").Funcs(template.FuncMap{
+		"escape":    escapeForXML,
+		"cdata":     cdata,
+		"millis":    millis,
+		"firstLine": firstLine,
+	}).Parse(xml.Header + SonarTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(out, report)
+}
@@ -0,0 +1,107 @@
+package lib
+
+// TAP (Test Anything Protocol) output
+// see https://testanything.org/tap-version-14-specification.html
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// mmath_test.go:35: 2/3 != 0.666667
+	testFileLineRE = regexp.MustCompile(`([a-zA-Z0-9_./\\-]+_test\.go):([0-9]+)`)
+	goFileLineRE   = regexp.MustCompile(`([a-zA-Z0-9_./\\-]+\.go):([0-9]+)`)
+)
+
+// fileLine returns the first file:line in message ("", 0 if not found), test
+// files are preferred (panics start with the runtime files)
+func fileLine(message string) (string, int) {
+	tokens := testFileLineRE.FindStringSubmatch(message)
+	if tokens == nil {
+		tokens = goFileLineRE.FindStringSubmatch(message)
+	}
+	if tokens == nil {
+		return "", 0
+	}
+	line, _ := strconv.Atoi(tokens[2])
+	return tokens[1], line
+}
+
+// tapEscape escapes characters that have a meaning in a TAP test line
+func tapEscape(name string) string {
+	name = strings.Replace(name, `\`, `\\`, -1)
+	return strings.Replace(name, "#", `\#`, -1)
+}
+
+// tapWriter writes TAP, keeping the first error
+type tapWriter struct {
+	out io.Writer
+	err error
+}
+
+func (w *tapWriter) printf(indent, format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.out, indent+format+"\n", args...)
+}
+
+// point writes a single test point followed by its YAML diagnostic block
+func (w *tapWriter) point(indent string, num int, name string, status Status, duration, message string) {
+	result := "ok"
+	if status == Failed || status == Errored {
+		result = "not ok"
+	}
+	directive := ""
+	if status == Skipped {
+		reason := strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+		directive = " # SKIP " + tapEscape(reason)
+	}
+	w.printf(indent, "%s %d - %s%s", result, num, tapEscape(name), strings.TrimRight(directive, " "))
+
+	w.printf(indent, "  ---")
+	w.printf(indent, "  duration_ms: %s", durationMs(duration))
+	if status == Failed || status == Errored {
+		w.printf(indent, "  severity: %s", strings.ToLower(status.String()))
+		if message != "" {
+			// Explicit indentation, messages might start with spaces
+			w.printf(indent, "  message: |2-")
+			for _, line := range strings.Split(message, "\n") {
+				if line == "" {
+					w.printf("", "")
+					continue
+				}
+				w.printf(indent, "    %s", line)
+			}
+		}
+		if file, line := fileLine(message); file != "" {
+			w.printf(indent, "  at:")
+			w.printf(indent, "    file: %q", file)
+			w.printf(indent, "    line: %d", line)
+		}
+	}
+	w.printf(indent, "  ...")
+}
+
+// WriteTAP writes suites to out in TAP version 14, every suite is a subtest
+func WriteTAP(suites []*Suite, out io.Writer) error {
+	w := &tapWriter{out: out}
+	w.printf("", "TAP version 14")
+	for i, suite := range suites {
+		w.printf("", "# Subtest: %s", suite.Name)
+		w.printf("    ", "1..%d", len(suite.Tests))
+		for j, test := range suite.Tests {
+			w.point("    ", j+1, test.Name, test.Status, test.Time, test.Message)
+		}
+		status := Passed
+		if suite.NumFailed() > 0 || suite.NumErrored() > 0 {
+			status = Failed
+		}
+		w.point("", i+1, suite.Name, status, suite.Time, "")
+	}
+	w.printf("", "1..%d", len(suites))
+	return w.err
+}
@@ -0,0 +1,151 @@
+package lib
+
+// TeamCity service messages
+// see https://www.jetbrains.com/help/teamcity/service-messages.html
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var tcEscaper = strings.NewReplacer(
+	"|", "||",
+	"'", "|'",
+	"\n", "|n",
+	"\r", "|r",
+	"[", "|[",
+	"]", "|]",
+	"\u0085", "|x",
+	"\u2028", "|l",
+	"\u2029", "|p",
+)
+
+// tcWriter writes TeamCity service messages, keeping the first error
+type tcWriter struct {
+	out io.Writer
+	err error
+}
+
+// message writes a service message, attrs are name, value pairs
+func (w *tcWriter) message(name string, attrs ...string) {
+	if w.err != nil {
+		return
+	}
+	var buf strings.Builder
+	buf.WriteString("##teamcity[" + name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&buf, " %s='%s'", attrs[i], tcEscaper.Replace(attrs[i+1]))
+	}
+	buf.WriteString("]\n")
+	_, w.err = io.WriteString(w.out, buf.String())
+}
+
+// firstLine returns the first non empty line of message
+func firstLine(message string) string {
+	for _, line := range strings.Split(message, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// writeTest writes the messages of test, attrs (e.g. flowId) are added to
+// every message
+func (w *tcWriter) writeTest(test *Test, attrs ...string) {
+	msg := func(name string, testAttrs ...string) {
+		w.message(name, append(append([]string{"name", test.Name}, testAttrs...), attrs...)...)
+	}
+
+	msg("testStarted", "captureStandardOutput", "false")
+	if output := extraOutput(test); output != "" {
+		msg("testStdOut", "out", output)
+	}
+	if test.ErrOutput != "" {
+		msg("testStdErr", "out", test.ErrOutput)
+	}
+	switch test.Status {
+	case Failed, Errored:
+		message := firstLine(test.Message)
+		if message == "" {
+			message = strings.ToLower(test.Status.String())
+		}
+		msg("testFailed", "message", message, "details", test.Message)
+	case Skipped:
+		msg("testIgnored", "message", firstLine(test.Message))
+	}
+	msg("testFinished", "duration", strconv.FormatInt(millis(test.Time), 10))
+}
+
+// WriteTeamCitySuite writes TeamCity service messages for a single suite
+func WriteTeamCitySuite(suite *Suite, out io.Writer) error {
+	w := &tcWriter{out: out}
+	w.message("testSuiteStarted", "name", suite.Name)
+	for _, test := range suite.Tests {
+		w.writeTest(test)
+	}
+	w.message("testSuiteFinished", "name", suite.Name)
+	return w.err
+}
+
+// TeamCityStream is a TestListener writing TeamCity service messages for every
+// test as soon as it's done. Packages may run in parallel, so messages have
+// the suite name as flowId.
+type TeamCityStream struct {
+	w       *tcWriter
+	started map[*Suite]bool
+	written map[*Test]bool
+}
+
+// NewTeamCityStream returns a TeamCityStream writing to out
+func NewTeamCityStream(out io.Writer) *TeamCityStream {
+	return &TeamCityStream{
+		w:       &tcWriter{out: out},
+		started: make(map[*Suite]bool),
+		written: make(map[*Test]bool),
+	}
+}
+
+// start writes testSuiteStarted for suite, once
+func (s *TeamCityStream) start(suite *Suite) {
+	if !s.started[suite] {
+		s.w.message("testSuiteStarted", "name", suite.Name, "flowId", suite.Name)
+		s.started[suite] = true
+	}
+}
+
+// TestDone writes the messages of test
+func (s *TeamCityStream) TestDone(suite *Suite, test *Test) {
+	s.start(suite)
+	s.w.writeTest(test, "flowId", suite.Name)
+	s.written[test] = true
+}
+
+// SuiteDone writes the tests of suite not written yet and ends the suite
+func (s *TeamCityStream) SuiteDone(suite *Suite) {
+	s.start(suite)
+	for _, test := range suite.Tests {
+		if !s.written[test] {
+			s.w.writeTest(test, "flowId", suite.Name)
+		}
+		delete(s.written, test)
+	}
+	s.w.message("testSuiteFinished", "name", suite.Name, "flowId", suite.Name)
+	delete(s.started, suite)
+}
+
+// Err returns the first write error (nil if no error)
+func (s *TeamCityStream) Err() error {
+	return s.w.err
+}
+
+// WriteTeamCity writes TeamCity service messages for suites
+func WriteTeamCity(suites []*Suite, out io.Writer) error {
+	for _, suite := range suites {
+		if err := WriteTeamCitySuite(suite, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
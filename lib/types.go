@@ -1,5 +1,12 @@
 package lib
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Status is test status
 type Status int
 
@@ -9,22 +16,139 @@ const (
 	Failed
 	Skipped
 	Passed
+	Errored // panics, timeouts, build failures ...
 )
 
+// String returns the status name
+func (s Status) String() string {
+	switch s {
+	case Failed:
+		return "Failed"
+	case Skipped:
+		return "Skipped"
+	case Passed:
+		return "Passed"
+	case Errored:
+		return "Errored"
+	}
+	return "Unknown"
+}
+
+// MarshalJSON encodes status as its lower case name (e.g. "failed")
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(s.String()))
+}
+
+// UnmarshalJSON decodes status from its name
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for _, status := range []Status{Failed, Skipped, Passed, Errored} {
+		if strings.EqualFold(name, status.String()) {
+			*s = status
+			return nil
+		}
+	}
+	*s = UnknownStatus
+	return nil
+}
+
 // Test data structure
 type Test struct {
-	Name, Time, Message string
-	Status              Status
-	AppendedErrorOutput	bool
-	isParentTest		bool
+	Name                string `json:"name"`
+	Time                string `json:"time"`
+	Message             string `json:"message,omitempty"`
+	Status              Status `json:"status"`
+	AppendedErrorOutput bool   `json:"-"`
+
+	// Class is the test class name (xunit XML input), when it's not the suite
+	// name
+	Class string `json:"class,omitempty"`
+
+	// Output is what the test printed, without the failure message when the
+	// input distinguishes them (e.g. -json). ErrOutput is what the test wrote
+	// to stderr, only known for some inputs (e.g. <system-err> in xunit XML).
+	Output    string `json:"output,omitempty"`
+	ErrOutput string `json:"errOutput,omitempty"`
+
+	// Sub tests (t.Run), Name of sub tests is the full name (e.g. "TestA/b")
+	Parent   *Test   `json:"-"`
+	Children []*Test `json:"-"`
+}
+
+// appendOutput appends lines to the test output
+func (test *Test) appendOutput(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	output := strings.Join(lines, "\n")
+	if test.Output == "" {
+		test.Output = output
+	} else {
+		test.Output += "\n" + output
+	}
+}
+
+// ClassName returns the class name of test in suite
+func (test *Test) ClassName(suite *Suite) string {
+	if test.Class != "" {
+		return test.Class
+	}
+	return suite.Name
+}
+
+// IsParent returns true if test has sub tests
+func (test *Test) IsParent() bool {
+	return len(test.Children) > 0
+}
+
+// AddChild adds a sub test
+func (test *Test) AddChild(child *Test) {
+	child.Parent = test
+	test.Children = append(test.Children, child)
+}
+
+// SubSuite returns a suite of the direct sub tests of test in suite, the sub
+// suite has the name of suite (the package)
+func (test *Test) SubSuite(suite *Suite) *Suite {
+	return &Suite{
+		Name:  suite.Name,
+		Time:  test.Time,
+		Tests: test.Children,
+	}
+}
+
+// Leaves returns a suite with all the tests under test that don't have sub
+// tests
+func (test *Test) Leaves() *Suite {
+	return &Suite{
+		Name:  test.Name,
+		Time:  test.Time,
+		Tests: leaves(test.Children),
+	}
+}
+
+// leaves returns all tests in tree that don't have sub tests
+func leaves(tests []*Test) []*Test {
+	var out []*Test
+	for _, test := range tests {
+		if test.IsParent() {
+			out = append(out, leaves(test.Children)...)
+		} else {
+			out = append(out, test)
+		}
+	}
+	return out
 }
 
 // Suite of tests (found in some unit testing frameworks)
 type Suite struct {
-	Name   string
-	Time   string
-	Status string
-	Tests  []*Test
+	Name   string  `json:"name"`
+	Time   string  `json:"time"`
+	Status string  `json:"status"`
+	Tests  []*Test `json:"tests"`
 }
 
 // NumPassed return number of passed tests in the suite
@@ -42,6 +166,11 @@ func (suite *Suite) NumFailed() int {
 	return suite.numStatus(Failed)
 }
 
+// NumErrored return number of errored tests in suite
+func (suite *Suite) NumErrored() int {
+	return suite.numStatus(Errored)
+}
+
 // numStatus returns the number of tests in status
 func (suite *Suite) numStatus(status Status) int {
 	count := 0
@@ -58,19 +187,97 @@ func (suite *Suite) Len() int {
 	return len(suite.Tests)
 }
 
+// Roots returns the tests in suite that are not sub tests
+func (suite *Suite) Roots() []*Test {
+	var roots []*Test
+	for _, test := range suite.Tests {
+		if test.Parent == nil {
+			roots = append(roots, test)
+		}
+	}
+	return roots
+}
+
+// RootSuite returns a suite with only the tests that are not sub tests
+func (suite *Suite) RootSuite() *Suite {
+	return &Suite{
+		Name:   suite.Name,
+		Time:   suite.Time,
+		Status: suite.Status,
+		Tests:  suite.Roots(),
+	}
+}
+
+// Leaves returns a suite with only the tests that don't have sub tests
+func (suite *Suite) Leaves() *Suite {
+	return &Suite{
+		Name:   suite.Name,
+		Time:   suite.Time,
+		Status: suite.Status,
+		Tests:  leaves(suite.Roots()),
+	}
+}
+
 // Suites is a list of suites
 type Suites []*Suite
 
-// HasFailures return true is there's at least one failing suite
+// HasFailures return true is there's at least one failing (or errored) suite
 func (s Suites) HasFailures() bool {
 	for _, suite := range s {
-		if suite.NumFailed() > 0 {
+		if suite.NumFailed() > 0 || suite.NumErrored() > 0 {
 			return true
 		}
 	}
 	return false
 }
 
+// MergeSuites merges several lists of suites to one. Suites with the same name
+// (e.g. from sharded runs) are merged to one suite.
+func MergeSuites(all ...Suites) Suites {
+	merged := Suites{}
+	byName := make(map[string]*Suite)
+	for _, suites := range all {
+		for _, suite := range suites {
+			if prev, ok := byName[suite.Name]; ok {
+				prev.merge(suite)
+				continue
+			}
+			clone := *suite
+			clone.Tests = append([]*Test(nil), suite.Tests...)
+			merged = append(merged, &clone)
+			byName[suite.Name] = &clone
+		}
+	}
+	return merged
+}
+
+// merge adds tests of other to suite, tests already in suite (same class and
+// name) are ignored
+func (suite *Suite) merge(other *Suite) {
+	type key struct{ class, name string }
+	seen := make(map[key]bool)
+	for _, test := range suite.Tests {
+		seen[key{test.ClassName(suite), test.Name}] = true
+	}
+	for _, test := range other.Tests {
+		if !seen[key{test.ClassName(other), test.Name}] {
+			suite.Tests = append(suite.Tests, test)
+		}
+	}
+
+	time, err1 := strconv.ParseFloat(suite.Time, 64)
+	otherTime, err2 := strconv.ParseFloat(other.Time, 64)
+	if err1 == nil && err2 == nil {
+		suite.Time = fmt.Sprintf("%.3f", time+otherTime)
+	} else if err1 != nil {
+		suite.Time = other.Time
+	}
+
+	if other.Status == "FAIL" {
+		suite.Status = other.Status
+	}
+}
+
 // SuiteStack is a stack of test suites
 type SuiteStack struct {
 	nodes []*Suite
@@ -78,4 +78,83 @@ func TestMultipleSuits(t *testing.T) {
 			t.Fatal("Expected true, got: false")
 		}
 	})
+	t.Run("WithErrors", func(t *testing.T) {
+		errored := Suite{Tests: []*Test{{Status: Errored}}}
+		suites := append(golden, &errored)
+		if !suites.HasFailures() {
+			t.Fatal("Expected true, got: false")
+		}
+		if errors := errored.NumErrored(); errors != 1 {
+			t.Fatal("Expected 1 errors, got:", errors)
+		}
+	})
+}
+
+func TestSubTests(t *testing.T) {
+	parent := &Test{Name: "TestA", Status: Failed}
+	child := &Test{Name: "TestA/b", Status: Failed}
+	grandChild1 := &Test{Name: "TestA/b/c", Status: Failed}
+	grandChild2 := &Test{Name: "TestA/b/d", Status: Passed}
+	parent.AddChild(child)
+	child.AddChild(grandChild1)
+	child.AddChild(grandChild2)
+	other := &Test{Name: "TestB", Status: Skipped}
+
+	suite := &Suite{
+		Tests: []*Test{parent, child, grandChild1, grandChild2, other},
+	}
+	t.Run("Parent", func(t *testing.T) {
+		if grandChild1.Parent != child || child.Parent != parent {
+			t.Fatal("Bad parent")
+		}
+		if !child.IsParent() || grandChild2.IsParent() {
+			t.Fatal("Bad IsParent")
+		}
+	})
+	t.Run("Roots", func(t *testing.T) {
+		if roots := suite.Roots(); len(roots) != 2 {
+			t.Fatal("Expected 2 roots, got:", len(roots))
+		}
+	})
+	t.Run("Leaves", func(t *testing.T) {
+		leaves := suite.Leaves()
+		if count := leaves.Len(); count != 3 {
+			t.Fatal("Expected 3 leaves, got:", count)
+		}
+		if failures := leaves.NumFailed(); failures != 1 {
+			t.Fatal("Expected 1 failures, got:", failures)
+		}
+		if count := parent.Leaves().Len(); count != 2 {
+			t.Fatal("Expected 2 leaves, got:", count)
+		}
+	})
+}
+
+func TestMergeSuites(t *testing.T) {
+	shard1 := Suites{
+		{Name: "a", Time: "0.5", Tests: []*Test{{Name: "TestA1"}}},
+		{Name: "b", Time: "1", Tests: []*Test{{Name: "TestB1"}}},
+	}
+	shard2 := Suites{
+		{Name: "a", Time: "0.25", Status: "FAIL", Tests: []*Test{{Name: "TestA2"}, {Name: "TestA1"}}},
+		{Name: "c", Time: "2", Tests: []*Test{{Name: "TestC1"}}},
+	}
+
+	merged := MergeSuites(shard1, shard2)
+	if len(merged) != 3 {
+		t.Fatal("Expected 3 suites, got:", len(merged))
+	}
+	suite := merged[0]
+	if count := suite.Len(); count != 2 {
+		t.Fatal("Expected 2 tests, got:", count)
+	}
+	if suite.Time != "0.750" {
+		t.Fatal("Expected time of 0.750, got:", suite.Time)
+	}
+	if suite.Status != "FAIL" {
+		t.Fatal("Expected FAIL status, got:", suite.Status)
+	}
+	if count := shard1[0].Len(); count != 1 {
+		t.Fatal("Input suite modified, tests:", count)
+	}
 }
@@ -0,0 +1,165 @@
+package lib
+
+// Output writers registry
+import (
+	"io"
+	"sort"
+	"time"
+)
+
+// RunInfo is information about the test run passed to writers
+type RunInfo struct {
+	Start    time.Time // Test run start time
+	Multi    bool      // Use multiple suites format even for one suite (e.g. merged inputs)
+	Module   string    // Go module path ("" if unknown)
+	Warnings io.Writer // Warnings are written here (optional)
+}
+
+// Writer writes suites in some format
+type Writer interface {
+	Write(suites Suites, out io.Writer, info RunInfo) error
+}
+
+// WriterFunc is a function implementing Writer
+type WriterFunc func(suites Suites, out io.Writer, info RunInfo) error
+
+// Write calls f
+func (f WriterFunc) Write(suites Suites, out io.Writer, info RunInfo) error {
+	return f(suites, out, info)
+}
+
+// Describer is implemented by writers that have a description (shown in
+// "-format help")
+type Describer interface {
+	Description() string
+}
+
+// TemplateWriter writes suites using an XML template (see WriteXML)
+type TemplateWriter struct {
+	Template      string
+	MultiTemplate string // Used when there's more than one suite (optional)
+	Desc          string
+}
+
+// Write writes suites with the template
+func (w *TemplateWriter) Write(suites Suites, out io.Writer, info RunInfo) error {
+	xmlTemplate := w.Template
+	if w.MultiTemplate != "" && (info.Multi || len(suites) > 1) {
+		xmlTemplate = w.MultiTemplate
+	}
+	return WriteXML(suites, out, xmlTemplate, info.Start)
+}
+
+// Description returns the writer description
+func (w *TemplateWriter) Description() string {
+	return w.Desc
+}
+
+// describedWriter adds a description to a WriterFunc
+type describedWriter struct {
+	WriterFunc
+	desc string
+}
+
+func (w *describedWriter) Description() string {
+	return w.desc
+}
+
+var writers = make(map[string]Writer)
+
+// RegisterWriter registers a writer for format name, it replaces an existing
+// writer with the same name
+func RegisterWriter(name string, writer Writer) {
+	writers[name] = writer
+}
+
+// GetWriter returns the writer for format name
+func GetWriter(name string) (Writer, bool) {
+	writer, ok := writers[name]
+	return writer, ok
+}
+
+// WriterNames returns the names of registered writers, sorted
+func WriterNames() []string {
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterWriter("xunit", &TemplateWriter{
+		Template:      XUnitTemplate,
+		MultiTemplate: XMLMultiTemplate,
+		Desc:          "xunit/JUnit XML (default)",
+	})
+	RegisterWriter("bamboo", &TemplateWriter{
+		Template: XMLMultiTemplate,
+		Desc:     "xunit XML compatible with Atlassian's Bamboo",
+	})
+	RegisterWriter("xunit-nested", &TemplateWriter{
+		Template:      XUnitNestedTemplate,
+		MultiTemplate: XMLMultiNestedTemplate,
+		Desc:          "xunit XML with sub tests nested in their parent test",
+	})
+	RegisterWriter("xunitnet", &TemplateWriter{
+		Template: XUnitNetTemplate,
+		Desc:     "xunit.net (v1) XML",
+	})
+	RegisterWriter("xunitnet2", &TemplateWriter{
+		Template: XUnitNet2Template,
+		Desc:     "xUnit.net v2 XML",
+	})
+	RegisterWriter("nunit", &TemplateWriter{
+		Template: NUnitTemplate,
+		Desc:     "NUnit 3 XML",
+	})
+	RegisterWriter("trx", &TemplateWriter{
+		Template: TRXTemplate,
+		Desc:     "Visual Studio test results (.trx)",
+	})
+	RegisterWriter("tap", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteTAP(suites, out)
+		},
+		"TAP (Test Anything Protocol) version 14",
+	})
+	RegisterWriter("teamcity", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteTeamCity(suites, out)
+		},
+		"TeamCity service messages",
+	})
+	RegisterWriter("sonar", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteSonar(suites, out, &TestLocator{Module: info.Module, Root: ".", Warnings: info.Warnings})
+		},
+		"SonarQube generic test execution XML",
+	})
+	RegisterWriter("markdown", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteMarkdown(suites, out)
+		},
+		"Markdown summary",
+	})
+	RegisterWriter("html", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteHTML(suites, out, info.Start)
+		},
+		"Self contained HTML report",
+	})
+	RegisterWriter("json", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteJSON(suites, out, info.Start)
+		},
+		"JSON report",
+	})
+	RegisterWriter("ctrf", &describedWriter{
+		func(suites Suites, out io.Writer, info RunInfo) error {
+			return WriteCTRF(suites, out, info.Start)
+		},
+		"Common Test Report Format (JSON)",
+	})
+}
@@ -0,0 +1,158 @@
+package lib
+
+// XML input
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xmlMessage is a failure, error or skipped element
+type xmlMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// text returns the message text, defaults to the message attribute
+func (m *xmlMessage) text() string {
+	if text := strings.TrimSpace(m.Text); text != "" {
+		return text
+	}
+	return m.Message
+}
+
+type xmlTestCase struct {
+	Name      string      `xml:"name,attr"`
+	ClassName string      `xml:"classname,attr"`
+	Time      string      `xml:"time,attr"`
+	Failure   *xmlMessage `xml:"failure"`
+	Error     *xmlMessage `xml:"error"`
+	Skipped   *xmlMessage `xml:"skipped"`
+	SystemOut string      `xml:"system-out"`
+	SystemErr string      `xml:"system-err"`
+}
+
+type xmlTestSuite struct {
+	Name      string         `xml:"name,attr"`
+	Time      string         `xml:"time,attr"`
+	TestCases []xmlTestCase  `xml:"testcase"`
+	Suites    []xmlTestSuite `xml:"testsuite"` // nested suites
+}
+
+type xmlTestSuites struct {
+	Suites []xmlTestSuite `xml:"testsuite"`
+}
+
+// test converts an XML test case of suite to a Test
+func (tc *xmlTestCase) test(suite string) *Test {
+	test := &Test{
+		Name:      tc.Name,
+		Time:      seconds(tc.Time),
+		Status:    Passed,
+		Output:    strings.TrimSpace(tc.SystemOut),
+		ErrOutput: strings.TrimSpace(tc.SystemErr),
+	}
+
+	if tc.ClassName != suite {
+		test.Class = tc.ClassName
+	}
+
+	switch {
+	case tc.Error != nil:
+		test.Status = Errored
+		test.Message = tc.Error.text()
+	case tc.Failure != nil:
+		test.Status = Failed
+		test.Message = tc.Failure.text()
+	case tc.Skipped != nil:
+		test.Status = Skipped
+		test.Message = tc.Skipped.text()
+	}
+
+	return test
+}
+
+// tests returns all the tests in the suite, including nested suites
+func (ts *xmlTestSuite) tests() []*Test {
+	var tests []*Test
+	for i := range ts.TestCases {
+		tests = append(tests, ts.TestCases[i].test(ts.Name))
+	}
+	for i := range ts.Suites {
+		tests = append(tests, ts.Suites[i].tests()...)
+	}
+	return tests
+}
+
+// suite converts an XML test suite to a Suite
+func (ts *xmlTestSuite) suite(suitePrefix string) *Suite {
+	suite := &Suite{
+		Name:  suitePrefix + ts.Name,
+		Time:  seconds(ts.Time),
+		Tests: ts.tests(),
+	}
+
+	if ts.Time == "" {
+		// go2xunit (and others) don't emit suite time
+		total := 0.0
+		for _, test := range suite.Tests {
+			testTime, _ := strconv.ParseFloat(test.Time, 64)
+			total += testTime
+		}
+		suite.Time = fmt.Sprintf("%.3f", total)
+	}
+
+	if suite.NumFailed() > 0 || suite.NumErrored() > 0 {
+		suite.Status = "FAIL"
+	} else {
+		suite.Status = "ok"
+	}
+
+	return suite
+}
+
+// ParseXUnit parses JUnit/xunit XML (<testsuites> or <testsuite> root)
+func ParseXUnit(rd io.Reader, suitePrefix string) (Suites, error) {
+	dec := xml.NewDecoder(rd)
+	var xmlSuites []xmlTestSuite
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no testsuites or testsuite element found")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad XML - %s", err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "testsuites":
+			var ts xmlTestSuites
+			if err := dec.DecodeElement(&ts, &start); err != nil {
+				return nil, fmt.Errorf("bad XML - %s", err)
+			}
+			xmlSuites = ts.Suites
+		case "testsuite":
+			var ts xmlTestSuite
+			if err := dec.DecodeElement(&ts, &start); err != nil {
+				return nil, fmt.Errorf("bad XML - %s", err)
+			}
+			xmlSuites = []xmlTestSuite{ts}
+		default:
+			return nil, fmt.Errorf("unknown root element - %s", start.Name.Local)
+		}
+		break
+	}
+
+	suites := Suites{}
+	for i := range xmlSuites {
+		suites = append(suites, xmlSuites[i].suite(suitePrefix))
+	}
+	return suites, nil
+}
@@ -3,10 +3,14 @@ package lib
 // XML output
 import (
 	"bytes"
+	"crypto/sha1"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -14,12 +18,16 @@ import (
 const (
 	// XUnitTemplate is XML template for xunit style reporting
 	XUnitTemplate string = `
-{{range $suite := .Suites}}  <testsuite name="{{.Name | escape}}" tests="{{.Len}}" errors="0" failures="{{.NumFailed}}" skip="{{.NumSkipped}}">
-{{range  $test := $suite.Tests}}    <testcase classname="{{$suite.Name | escape}}" name="{{$test.Name | escape}}" time="{{$test.Time}}">
+{{range $suite := .Suites}}  <testsuite name="{{.Name | escape}}" tests="{{.Len}}" errors="{{.NumErrored}}" failures="{{.NumFailed}}" skip="{{.NumSkipped}}">
+{{range  $test := $suite.Tests}}    <testcase classname="{{$test.ClassName $suite | escape}}" name="{{$test.Name | escape}}" time="{{$test.Time | escape}}">
 {{if eq $test.Status $.Skipped }}      <skipped/> {{end}}
 {{if eq $test.Status $.Failed }}      <failure type="go.error" message="error">
-        <![CDATA[{{$test.Message}}]]>
-      </failure>{{end}}    </testcase>
+        {{cdata $test.Message}}
+      </failure>{{end}}{{if eq $test.Status $.Errored }}      <error type="go.error" message="error">
+        {{cdata $test.Message}}
+      </error>{{end}}{{with extraOutput $test}}
+      <system-out>{{cdata .}}</system-out>{{end}}{{if $test.ErrOutput}}
+      <system-err>{{cdata $test.ErrOutput}}</system-err>{{end}}    </testcase>
 {{end}}  </testsuite>
 {{end}}`
 
@@ -28,39 +36,201 @@ const (
 <testsuites>` + XUnitTemplate + `</testsuites>
 `
 
-	// XUnitNetTemplate is XML template for xunit.net
+	// XUnitNestedTemplate is XML template for xunit style reporting where sub
+	// tests are nested in a testsuite of their parent test
+	XUnitNestedTemplate string = `
+{{range $suite := .Suites}}{{with $leaves := .Leaves}}  <testsuite name="{{$suite.Name | escape}}" tests="{{$leaves.Len}}" errors="{{$leaves.NumErrored}}" failures="{{$leaves.NumFailed}}" skip="{{$leaves.NumSkipped}}">
+{{template "nested" $suite.RootSuite}}  </testsuite>
+{{end}}{{end}}` + `{{define "nested"}}{{range $test := .Tests}}{{if $test.IsParent}}{{with $leaves := $test.Leaves}}    <testsuite name="{{$test.Name | escape}}" tests="{{$leaves.Len}}" errors="{{$leaves.NumErrored}}" failures="{{$leaves.NumFailed}}" skip="{{$leaves.NumSkipped}}" time="{{$test.Time | escape}}">
+{{template "nested" ($test.SubSuite $)}}{{if $test.Output}}      <system-out>{{cdata $test.Output}}</system-out>
+{{end}}    </testsuite>
+{{end}}{{else}}    <testcase classname="{{$test.ClassName $ | escape}}" name="{{$test.Name | escape}}" time="{{$test.Time | escape}}">
+{{if eq $test.Status.String "Skipped"}}      <skipped/> {{end}}
+{{if eq $test.Status.String "Failed"}}      <failure type="go.error" message="error">
+        {{cdata $test.Message}}
+      </failure>{{end}}{{if eq $test.Status.String "Errored"}}      <error type="go.error" message="error">
+        {{cdata $test.Message}}
+      </error>{{end}}{{with extraOutput $test}}
+      <system-out>{{cdata .}}</system-out>{{end}}{{if $test.ErrOutput}}
+      <system-err>{{cdata $test.ErrOutput}}</system-err>{{end}}    </testcase>
+{{end}}{{end}}{{end}}`
+
+	// XMLMultiNestedTemplate is nested template when we have multiple suites
+	XMLMultiNestedTemplate string = `
+<testsuites>` + XUnitNestedTemplate + `</testsuites>
+`
+
+	// XUnitNetTemplate is XML template for xunit.net (v1)
 	// see https://xunit.codeplex.com/wikipage?title=XmlFormat
 	XUnitNetTemplate string = `
 <assembly name="{{.Assembly | escape}}"
-          run-date="{{.RunDate}}" run-time="{{.RunTime}}"
+          run-date="{{.RunDate | escape}}" run-time="{{.RunTime | escape}}"
           configFile="none"
-          time="{{.Time}}"
+          time="{{.Time | escape}}"
           total="{{.Len}}"
           passed="{{.NumPassed}}"
           failed="{{.NumFailed}}"
           skipped="{{.NumSkipped}}"
+          errors="{{.NumErrored}}"
           environment="n/a"
           test-framework="golang">
 {{range $suite := .Suites}}
-    <class time="{{.Time}}" name="{{.Name | escape}}"
+    <class time="{{.Time | escape}}" name="{{.Name | escape}}"
   	     total="{{.Len}}"
   	     passed="{{.NumPassed}}"
   	     failed="{{.NumFailed}}"
-  	     skipped="{{.NumSkipped}}">
+  	     skipped="{{.NumSkipped}}"
+  	     errors="{{.NumErrored}}">
 {{range  $test := $suite.Tests}}
         <test name="{{$test.Name | escape}}"
           type="test"
           method="{{$test.Name | escape}}"
-          result={{if eq $test.Status $.Skipped }}"Skip"{{else if eq $test.Status $.Failed }}"Fail"{{else if eq $test.Status $.Passed }}"Pass"{{end}}
-          time="{{$test.Time}}">
+          result={{if eq $test.Status $.Skipped }}"Skip"{{else if eq $test.Status $.Failed }}"Fail"{{else if eq $test.Status $.Errored }}"Fail"{{else if eq $test.Status $.Passed }}"Pass"{{end}}
+          time="{{$test.Time | escape}}">
         {{if eq $test.Status $.Failed }}  <failure exception-type="go.error">
-             <message><![CDATA[{{$test.Message}}]]></message>
+             <message>{{cdata $test.Message}}</message>
       	  </failure>
+      	{{end}}{{if eq $test.Status $.Errored }}  <failure exception-type="go.fatal">
+             <message>{{cdata $test.Message}}</message>
+      	  </failure>
+      	{{end}}{{with extraOutput $test}}  <output>{{cdata .}}</output>
       	{{end}}</test>
 {{end}}
     </class>
 {{end}}
 </assembly>
+`
+
+	// XUnitNet2Template is XML template for xUnit.net v2
+	// see https://xunit.net/docs/format-xml-v2
+	XUnitNet2Template string = `
+<assemblies>
+  <assembly name="{{.Assembly | escape}}" run-date="{{.RunDate | escape}}" run-time="{{.RunTime | escape}}" config-file="none" test-framework="golang" environment="n/a" time="{{.Time | escape}}" total="{{.Len}}" passed="{{.NumPassed}}" failed="{{add .NumFailed .NumErrored}}" skipped="{{.NumSkipped}}" errors="0">
+    <errors />
+{{range $suite := .Suites}}    <collection name="{{$suite.Name | escape}}" time="{{$suite.Time | escape}}" total="{{$suite.Len}}" passed="{{$suite.NumPassed}}" failed="{{add $suite.NumFailed $suite.NumErrored}}" skipped="{{$suite.NumSkipped}}">
+{{range $test := $suite.Tests}}      <test name="{{$suite.Name | escape}}.{{$test.Name | escape}}" type="{{$suite.Name | escape}}" method="{{$test.Name | escape}}" time="{{seconds $test.Time}}" result={{if eq $test.Status $.Skipped }}"Skip"{{else if or (eq $test.Status $.Failed) (eq $test.Status $.Errored) }}"Fail"{{else}}"Pass"{{end}}>
+        <traits>
+          <trait name="package" value="{{$suite.Name | escape}}" />
+        </traits>
+{{if eq $test.Status $.Skipped }}        <reason>{{cdata $test.Message}}</reason>
+{{end}}{{if $test.Output}}        <output>{{cdata $test.Output}}</output>
+{{end}}{{if eq $test.Status $.Failed }}        <failure exception-type="go.error">
+          <message>{{cdata $test.Message}}</message>
+          <stack-trace>{{cdata (stackTrace $test.Message)}}</stack-trace>
+        </failure>
+{{else if eq $test.Status $.Errored }}        <failure exception-type="go.fatal">
+          <message>{{cdata $test.Message}}</message>
+          <stack-trace>{{cdata (stackTrace $test.Message)}}</stack-trace>
+        </failure>
+{{end}}      </test>
+{{end}}    </collection>
+{{end}}  </assembly>
+</assemblies>
+`
+
+	// NUnitTemplate is XML template for NUnit 3
+	// see https://docs.nunit.org/articles/nunit/technical-notes/usage/Test-Result-XML-Format.html
+	NUnitTemplate string = `
+<test-run id="1" name="{{.Assembly | escape}}" fullname="{{.Assembly | escape}}"
+          testcasecount="{{.Len}}"
+          result="{{if or .NumFailed .NumErrored}}Failed{{else}}Passed{{end}}"
+          total="{{.Len}}"
+          passed="{{.NumPassed}}"
+          failed="{{add .NumFailed .NumErrored}}"
+          warnings="0"
+          inconclusive="0"
+          skipped="{{.NumSkipped}}"
+          asserts="0"
+          engine-version="3.0"
+          start-time="{{isoTime .Start}}"
+          end-time="{{isoTime (addTime .Start .Time)}}"
+          duration="{{seconds .Time}}">
+  <test-suite type="Assembly" id="1-0" name="{{.Assembly | escape}}" fullname="{{.Assembly | escape}}"
+              runstate="Runnable"
+              testcasecount="{{.Len}}"
+              result="{{if or .NumFailed .NumErrored}}Failed{{else}}Passed{{end}}"
+              start-time="{{isoTime .Start}}"
+              end-time="{{isoTime (addTime .Start .Time)}}"
+              duration="{{seconds .Time}}"
+              total="{{.Len}}"
+              passed="{{.NumPassed}}"
+              failed="{{add .NumFailed .NumErrored}}"
+              warnings="0"
+              inconclusive="0"
+              skipped="{{.NumSkipped}}"
+              asserts="0">
+{{range $i, $suite := .Suites}}    <test-suite type="TestFixture" id="1-{{add $i 1}}" name="{{$suite.Name | escape}}" fullname="{{$suite.Name | escape}}" classname="{{$suite.Name | escape}}"
+                runstate="Runnable"
+                testcasecount="{{$suite.Len}}"
+                result="{{if or $suite.NumFailed $suite.NumErrored}}Failed{{else}}Passed{{end}}"
+                start-time="{{isoTime $.Start}}"
+                end-time="{{isoTime (addTime $.Start $suite.Time)}}"
+                duration="{{seconds $suite.Time}}"
+                total="{{$suite.Len}}"
+                passed="{{$suite.NumPassed}}"
+                failed="{{add $suite.NumFailed $suite.NumErrored}}"
+                warnings="0"
+                inconclusive="0"
+                skipped="{{$suite.NumSkipped}}"
+                asserts="0">
+{{range $j, $test := $suite.Tests}}      <test-case id="1-{{add $i 1}}-{{$j}}" name="{{$test.Name | escape}}" fullname="{{$suite.Name | escape}}.{{$test.Name | escape}}" methodname="{{$test.Name | escape}}" classname="{{$suite.Name | escape}}"
+                 runstate="Runnable"
+                 result={{if eq $test.Status $.Skipped }}"Skipped" label="Ignored"{{else if eq $test.Status $.Failed }}"Failed"{{else if eq $test.Status $.Errored }}"Failed" label="Error"{{else}}"Passed"{{end}}
+                 start-time="{{isoTime $.Start}}"
+                 end-time="{{isoTime (addTime $.Start $test.Time)}}"
+                 duration="{{seconds $test.Time}}"
+                 asserts="0">
+{{if eq $test.Status $.Skipped }}        <reason>
+          <message>{{cdata $test.Message}}</message>
+        </reason>
+{{else if or (eq $test.Status $.Failed) (eq $test.Status $.Errored) }}        <failure>
+          <message>{{cdata $test.Message}}</message>
+          <stack-trace>{{cdata (stackTrace $test.Message)}}</stack-trace>
+        </failure>
+{{end}}{{if $test.Output}}        <output>{{cdata $test.Output}}</output>
+{{end}}      </test-case>
+{{end}}    </test-suite>
+{{end}}  </test-suite>
+</test-run>
+`
+
+	// TRXTemplate is XML template for Visual Studio test results (.trx)
+	// see https://github.com/microsoft/vstest/blob/main/src/Microsoft.TestPlatform.Extensions.TrxLogger/XML/TrxSchema.xsd
+	TRXTemplate string = `
+<TestRun id="{{guid "run" .Assembly}}" name="{{.Assembly | escape}}" runUser="go2xunit" xmlns="http://microsoft.com/schemas/VisualStudio/TeamTest/2010">
+  <Times creation="{{trxTime .Start}}" queuing="{{trxTime .Start}}" start="{{trxTime .Start}}" finish="{{trxTime (addTime .Start .Time)}}" />
+  <Results>
+{{range $suite := .Suites}}{{range $test := $suite.Tests}}    <UnitTestResult executionId="{{guid "execution" $suite.Name $test.Name}}" testId="{{guid "test" $suite.Name $test.Name}}" testName="{{$test.Name | escape}}" computerName="go2xunit" duration="{{timeSpan $test.Time}}" startTime="{{trxTime $.Start}}" endTime="{{trxTime (addTime $.Start $test.Time)}}" testType="13cdc9d9-ddb5-4fa4-a97d-d965ccfc6d4b" outcome={{if eq $test.Status $.Skipped }}"NotExecuted"{{else if eq $test.Status $.Failed }}"Failed"{{else if eq $test.Status $.Errored }}"Error"{{else}}"Passed"{{end}} testListId="8c84fa94-04c1-424b-9868-57a2d4851a1d" relativeResultsDirectory="{{guid "execution" $suite.Name $test.Name}}">
+{{if or $test.Output (ne $test.Status $.Passed)}}      <Output>
+{{if $test.Output}}        <StdOut>{{cdata $test.Output}}</StdOut>
+{{end}}{{if $test.ErrOutput}}        <StdErr>{{cdata $test.ErrOutput}}</StdErr>
+{{end}}{{if eq $test.Status $.Skipped }}        <ErrorInfo>
+          <Message>{{cdata $test.Message}}</Message>
+        </ErrorInfo>
+{{else if ne $test.Status $.Passed }}        <ErrorInfo>
+          <Message>{{cdata $test.Message}}</Message>
+          <StackTrace>{{cdata (stackTrace $test.Message)}}</StackTrace>
+        </ErrorInfo>
+{{end}}      </Output>
+{{end}}    </UnitTestResult>
+{{end}}{{end}}  </Results>
+  <TestDefinitions>
+{{range $suite := .Suites}}{{range $test := $suite.Tests}}    <UnitTest name="{{$test.Name | escape}}" storage="{{$suite.Name | escape}}" id="{{guid "test" $suite.Name $test.Name}}">
+      <Execution id="{{guid "execution" $suite.Name $test.Name}}" />
+      <TestMethod codeBase="{{$suite.Name | escape}}" adapterTypeName="executor://go2xunit/" className="{{$suite.Name | escape}}" name="{{$test.Name | escape}}" />
+    </UnitTest>
+{{end}}{{end}}  </TestDefinitions>
+  <TestEntries>
+{{range $suite := .Suites}}{{range $test := $suite.Tests}}    <TestEntry testId="{{guid "test" $suite.Name $test.Name}}" executionId="{{guid "execution" $suite.Name $test.Name}}" testListId="8c84fa94-04c1-424b-9868-57a2d4851a1d" />
+{{end}}{{end}}  </TestEntries>
+  <TestLists>
+    <TestList name="Results Not in a List" id="8c84fa94-04c1-424b-9868-57a2d4851a1d" />
+    <TestList name="All Loaded Results" id="19431567-8539-422a-85d7-44ee4e166bda" />
+  </TestLists>
+  <ResultSummary outcome="{{if or .NumFailed .NumErrored}}Failed{{else}}Completed{{end}}">
+    <Counters total="{{.Len}}" executed="{{add .NumPassed (add .NumFailed .NumErrored)}}" passed="{{.NumPassed}}" failed="{{.NumFailed}}" error="{{.NumErrored}}" timeout="0" aborted="0" inconclusive="0" passedButRunAborted="0" notRunnable="0" notExecuted="{{.NumSkipped}}" disconnected="0" warning="0" completed="0" inProgress="0" pending="0" />
+  </ResultSummary>
+</TestRun>
 `
 )
 
@@ -70,15 +240,37 @@ type TestResults struct {
 	Assembly   string
 	RunDate    string
 	RunTime    string
+	Start      time.Time
 	Time       string
 	Len        int
 	NumPassed  int
 	NumFailed  int
 	NumSkipped int
+	NumErrored int
 
 	Skipped Status
 	Passed  Status
 	Failed  Status
+	Errored Status
+}
+
+// newTestResults returns the template data for suites
+func newTestResults(suites []*Suite, testTime time.Time) TestResults {
+	results := TestResults{
+		Suites:  suites,
+		RunDate: testTime.Format("2006-01-02"),
+		RunTime: testTime.Format("15:04:05"),
+		Start:   testTime,
+		Skipped: Skipped,
+		Passed:  Passed,
+		Failed:  Failed,
+		Errored: Errored,
+	}
+	if len(suites) > 0 {
+		results.Assembly = suites[len(suites)-1].Name
+	}
+	results.calcTotals()
+	return results
 }
 
 // calcTotals calculates grand total for all suites
@@ -88,46 +280,214 @@ func (r *TestResults) calcTotals() {
 		r.NumPassed += suite.NumPassed()
 		r.NumFailed += suite.NumFailed()
 		r.NumSkipped += suite.NumSkipped()
+		r.NumErrored += suite.NumErrored()
 
 		suiteTime, _ := strconv.ParseFloat(suite.Time, 64)
 		totalTime += suiteTime
 		r.Time = fmt.Sprintf("%.3f", totalTime)
 	}
-	r.Len = r.NumPassed + r.NumSkipped + r.NumFailed
+	r.Len = r.NumPassed + r.NumSkipped + r.NumFailed + r.NumErrored
+}
+
+// parseSeconds parses duration (in seconds), invalid and negative durations
+// are 0
+func parseSeconds(duration string) float64 {
+	secs, err := strconv.ParseFloat(duration, 64)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return secs
+}
+
+// seconds returns duration (in seconds) as a number
+func seconds(duration string) string {
+	return strconv.FormatFloat(parseSeconds(duration), 'f', -1, 64)
+}
+
+// millis returns duration (in seconds) in whole milliseconds
+func millis(duration string) int64 {
+	return int64(math.Round(parseSeconds(duration) * 1000))
+}
+
+// durationMs returns duration (in seconds) in milliseconds, with microsecond
+// precision
+func durationMs(duration string) string {
+	return strconv.FormatFloat(math.Round(parseSeconds(duration)*1e6)/1e3, 'f', -1, 64)
+}
+
+// isoTime formats t in NUnit's time format
+func isoTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05Z")
+}
+
+// toDuration converts duration in seconds to time.Duration
+func toDuration(duration string) time.Duration {
+	return time.Duration(parseSeconds(duration) * float64(time.Second))
+}
+
+// addTime returns start + duration (in seconds)
+func addTime(start time.Time, duration string) time.Time {
+	return start.Add(toDuration(duration))
+}
+
+// trxTime formats t in TRX time format
+func trxTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.0000000Z07:00")
+}
+
+// timeSpan formats duration (in seconds) as a .NET TimeSpan (hh:mm:ss.fffffff)
+func timeSpan(duration string) string {
+	d := toDuration(duration)
+	ticks := (d % time.Second) / 100 // .NET ticks are 100ns
+	d -= d % time.Second
+	return fmt.Sprintf("%02d:%02d:%02d.%07d",
+		int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, ticks)
+}
+
+// guid returns a GUID derived from names, so the same test always gets the
+// same id
+func guid(names ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(names, "\x00")))
+	sum[6] = (sum[6] & 0x0f) | 0x50 // version 5
+	sum[8] = (sum[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// stackTrace returns the goroutines dump part of a test message (panics)
+func stackTrace(message string) string {
+	loc := gtGoroutineRE.FindStringIndex(message)
+	if loc == nil {
+		return ""
+	}
+	return message[loc[0]:]
+}
+
+// add is used to sum counts in templates
+func add(a, b int) int {
+	return a + b
+}
+
+// extraOutput returns the output of test, unless it's already in the failure
+// message (text go test output has no separate message)
+func extraOutput(test *Test) string {
+	if (test.Status == Failed || test.Status == Errored) && test.Output == test.Message {
+		return ""
+	}
+	return test.Output
 }
 
 func escapeForXML(in string) (string, error) {
 	w := &bytes.Buffer{}
-	if err := xml.EscapeText(w, []byte(in)); err != nil {
+	if err := xml.EscapeText(w, []byte(sanitizeXML(in))); err != nil {
 		return "", fmt.Errorf("error escaping text: %s", err)
 	}
 	return w.String(), nil
 }
 
-// WriteXML exits xunit XML of tests to out
-func WriteXML(suites []*Suite, out io.Writer, xmlTemplate string, testTime time.Time) {
-	testsResult := TestResults{
-		Suites:   suites,
-		Assembly: suites[len(suites)-1].Name,
-		RunDate:  testTime.Format("2006-01-02"),
-		RunTime:  testTime.Format("15:04:05"),
-		Skipped:  Skipped,
-		Passed:   Passed,
-		Failed:   Failed,
+// isXMLChar returns true if r is allowed in XML 1.0
+// see https://www.w3.org/TR/xml/#charsets
+func isXMLChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r >= 0x20 && r <= 0xD7FF:
+		return true
+	case r >= 0xE000 && r <= 0xFFFD:
+		return true
+	case r >= 0x10000 && r <= 0x10FFFF:
+		return true
 	}
-	testsResult.calcTotals()
-	t := template.New("test template").Funcs(template.FuncMap{
-		"escape": escapeForXML,
-	})
+	return false
+}
+
+// sanitizeXML removes characters that are illegal in XML 1.0 (e.g. control
+// characters from binary output), invalid UTF-8 is replaced with U+FFFD
+func sanitizeXML(text string) string {
+	return strings.Map(func(r rune) rune {
+		if isXMLChar(r) {
+			return r
+		}
+		return -1
+	}, text)
+}
+
+// cdata wraps text in a CDATA section, "]]>" in text is split between two
+// sections and illegal characters are removed
+func cdata(text string) string {
+	text = sanitizeXML(text)
+	return "<![CDATA[" + strings.Replace(text, "]]>", "]]]]><![CDATA[>", -1) + "]]>"
+}
 
-	t, err := t.Parse(xml.Header + xmlTemplate)
+// toJSON returns v encoded as JSON
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
 	if err != nil {
-		fmt.Printf("Error in parse %v\n", err)
-		return
+		return "", err
 	}
-	err = t.Execute(out, testsResult)
+	return string(data), nil
+}
+
+// truncate returns the first n characters of text, "..." is added if text is
+// longer. The text is last so it can be used in pipelines ({{.Message |
+// truncate 100}}).
+func truncate(n int, text string) string {
+	runes := []rune(text)
+	if n < 0 || len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
+}
+
+// statusName returns the status name (e.g. "Failed")
+func statusName(status Status) string {
+	return status.String()
+}
+
+// TemplateFuncs returns the functions available in templates
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"escape":      escapeForXML,
+		"seconds":     seconds,
+		"isoTime":     isoTime,
+		"addTime":     addTime,
+		"trxTime":     trxTime,
+		"timeSpan":    timeSpan,
+		"guid":        guid,
+		"stackTrace":  stackTrace,
+		"add":         add,
+		"extraOutput": extraOutput,
+		"cdata":       cdata,
+		"json":        toJSON,
+		"durationMs":  durationMs,
+		"truncate":    truncate,
+		"statusName":  statusName,
+	}
+}
+
+// executeTemplate executes tmpl with TestResults of suites to out. The output
+// is written only if the template executed successfully.
+func executeTemplate(name, tmpl string, suites []*Suite, out io.Writer, testTime time.Time) error {
+	t, err := template.New(name).Funcs(TemplateFuncs()).Parse(tmpl)
 	if err != nil {
-		fmt.Printf("Error in execute %v\n", err)
-		return
+		return fmt.Errorf("bad template - %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newTestResults(suites, testTime)); err != nil {
+		return fmt.Errorf("can't execute template - %s", err)
 	}
+	_, err = buf.WriteTo(out)
+	return err
+}
+
+// WriteTemplate executes the text/template tmpl with TestResults of suites to
+// out, unlike WriteXML no XML header is added
+func WriteTemplate(suites []*Suite, out io.Writer, tmpl string, testTime time.Time) error {
+	return executeTemplate("user template", tmpl, suites, out, testTime)
+}
+
+// WriteXML writes xunit XML of tests to out. The report is written only if
+// the template executed successfully.
+func WriteXML(suites []*Suite, out io.Writer, xmlTemplate string, testTime time.Time) error {
+	return executeTemplate("test template", xml.Header+xmlTemplate, suites, out, testTime)
 }
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/tebeka/go2xunit/lib"
@@ -12,7 +16,7 @@ import (
 
 const (
 	// Version is the current version
-	Version = "1.4.10"
+	Version = "1.5.0"
 )
 
 // getInput return input io.File from file name, if file name is - it will
@@ -35,11 +39,34 @@ func getOutput(filename string) (*os.File, error) {
 	return os.Create(filename)
 }
 
+// expandInputs expands glob patterns in input file names
+func expandInputs(patterns []string) []string {
+	if len(patterns) == 0 {
+		return []string{""}
+	}
+
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			// Not a pattern (or "-"), will fail on open if doesn't exist
+			names = append(names, pattern)
+			continue
+		}
+		names = append(names, matches...)
+	}
+	return names
+}
+
 // getIO returns input and output streams from file names
-func getIO(inFile, outFile string) (*os.File, io.Writer, error) {
-	input, err := getInput(inFile)
-	if err != nil {
-		return nil, nil, fmt.Errorf("can't open %s for reading: %s", inFile, err)
+func getIO(inFiles []string, outFile string) ([]*os.File, io.Writer, error) {
+	var inputs []*os.File
+	for _, inFile := range expandInputs(inFiles) {
+		input, err := getInput(inFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't open %s for reading: %s", inFile, err)
+		}
+		inputs = append(inputs, input)
 	}
 
 	output, err := getOutput(outFile)
@@ -47,15 +74,274 @@ func getIO(inFile, outFile string) (*os.File, io.Writer, error) {
 		return nil, nil, fmt.Errorf("can't open %s for writing: %s", outFile, err)
 	}
 
-	return input, output, nil
+	return inputs, output, nil
+}
+
+// getConsole returns where to copy test output so it won't mix with the
+// report
+func getConsole(output io.Writer) io.Writer {
+	if output == os.Stdout {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// goModule returns the module path from go.mod in the current directory ("" if
+// there's none)
+func goModule() string {
+	data, err := ioutil.ReadFile("go.mod")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// parseFunc is a function parsing test output
+type parseFunc func(rd io.Reader, suitePrefix string) (lib.Suites, error)
+
+// getParser returns the parser matching command line flags, output is where
+// streamed reports are written
+func getParser(output io.Writer) parseFunc {
+	switch {
+	case args.isGocheck:
+		return lib.ParseGocheck
+	case args.isJSON && isStreamed():
+		return func(rd io.Reader, suitePrefix string) (lib.Suites, error) {
+			return streamTeamCityJSON(rd, suitePrefix, output)
+		}
+	case args.isJSON:
+		return lib.ParseGotestJSON
+	case isStreamed():
+		return func(rd io.Reader, suitePrefix string) (lib.Suites, error) {
+			return streamTeamCity(rd, suitePrefix, output)
+		}
+	}
+	return lib.ParseGotest
+}
+
+// isStreamed returns true if the report is written while parsing
+func isStreamed() bool {
+	return reportFormat() == "teamcity" && !args.isGocheck && !args.merge
+}
+
+// streamTeamCityJSON parses go test -json output and writes TeamCity service
+// messages for every test as soon as it's done
+func streamTeamCityJSON(rd io.Reader, suitePrefix string, output io.Writer) (lib.Suites, error) {
+	stream := lib.NewTeamCityStream(output)
+	suites, err := lib.ParseGotestJSONStream(rd, suitePrefix, stream)
+	if err != nil {
+		return nil, err
+	}
+	return suites, stream.Err()
+}
+
+// streamTeamCity parses gotest output and writes TeamCity service messages for
+// every suite (package) as soon as it's done. The package name is printed
+// only at the end of the package, so unlike with -json the progress is per
+// package.
+func streamTeamCity(rd io.Reader, suitePrefix string, output io.Writer) (lib.Suites, error) {
+	parser := lib.NewGtParser(rd, suitePrefix)
+	var suites lib.Suites
+	for parser.Scan() {
+		suite := parser.Suite()
+		if err := lib.WriteTeamCitySuite(suite, output); err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+	return suites, parser.Err()
+}
+
+// parseInput parses input, copying it to the console with -passthrough
+func parseInput(input io.Reader, output io.Writer) (lib.Suites, error) {
+	rd := input
+	if args.passthrough {
+		rd = io.TeeReader(input, getConsole(output))
+	}
+
+	parse := getParser(output)
+	suites, err := parse(rd, args.suitePrefix)
+	if args.passthrough {
+		// Copy what the parser didn't read
+		io.Copy(ioutil.Discard, rd)
+	}
+	return suites, err
+}
+
+// writeFile writes an additional report (e.g. -markdown) to fileName
+func writeFile(fileName string, write func(out io.Writer) error) error {
+	out, err := getOutput(fileName)
+	if err != nil {
+		return err
+	}
+	if err := write(out); err != nil {
+		return err
+	}
+	if out != os.Stdout {
+		return out.Close()
+	}
+	return nil
+}
+
+// closeOutput closes output if it's a file, errors (e.g. disk full) are fatal
+func closeOutput(output io.Writer) {
+	file, ok := output.(*os.File)
+	if !ok || file == os.Stdout {
+		return
+	}
+	if err := file.Close(); err != nil {
+		log.Fatalf("error: can't write report - %s", err)
+	}
+}
+
+// writeReport writes suites to output and closes it, merged is true when
+// suites were read from several inputs
+func writeReport(suites lib.Suites, output io.Writer, testTime time.Time, merged bool) {
+	defer closeOutput(output)
+
+	if args.githubActions {
+		err := lib.WriteGitHubAnnotations(suites, getConsole(output), goModule())
+		if err != nil {
+			log.Fatalf("error: can't write annotations - %s", err)
+		}
+	}
+
+	if args.markdownFile != "" {
+		err := writeFile(args.markdownFile, func(out io.Writer) error {
+			return lib.WriteMarkdown(suites, out)
+		})
+		if err != nil {
+			log.Fatalf("error: can't write markdown - %s", err)
+		}
+	}
+
+	if args.jsonOutFile != "" {
+		write := lib.WriteJSON
+		if args.jsonOutFormat == "ctrf" {
+			write = lib.WriteCTRF
+		}
+		err := writeFile(args.jsonOutFile, func(out io.Writer) error {
+			return write(suites, out, testTime)
+		})
+		if err != nil {
+			log.Fatalf("error: can't write JSON - %s", err)
+		}
+	}
+
+	if args.allureDir != "" {
+		if err := lib.WriteAllure(suites, args.allureDir, testTime); err != nil {
+			log.Fatalf("error: can't write Allure results - %s", err)
+		}
+	}
+
+	if args.htmlFile != "" {
+		err := writeFile(args.htmlFile, func(out io.Writer) error {
+			return lib.WriteHTML(suites, out, testTime)
+		})
+		if err != nil {
+			log.Fatalf("error: can't write HTML - %s", err)
+		}
+	}
+
+	if isStreamed() {
+		// Already written while parsing (see streamTeamCity)
+		return
+	}
+
+	if args.templateFile != "" {
+		if err := writeTemplate(suites, output, testTime); err != nil {
+			log.Fatalf("error: can't write report - %s", err)
+		}
+		return
+	}
+
+	name := reportFormat()
+	info := lib.RunInfo{
+		Start:    testTime,
+		Multi:    merged || name == "bamboo",
+		Module:   goModule(),
+		Warnings: os.Stderr,
+	}
+	if args.nested {
+		// validateArgs makes sure the format is xunit (or bamboo)
+		name = "xunit-nested"
+	}
+	writer, ok := lib.GetWriter(name)
+	if !ok {
+		log.Fatalf("error: unknown format - %s", name)
+	}
+	if err := writer.Write(suites, output, info); err != nil {
+		log.Fatalf("error: can't write report - %s", err)
+	}
+}
+
+// writeTemplate writes suites to output with the -template file
+func writeTemplate(suites lib.Suites, output io.Writer, testTime time.Time) error {
+	data, err := ioutil.ReadFile(args.templateFile)
+	if err != nil {
+		return err
+	}
+	return lib.WriteTemplate(suites, output, string(data), testTime)
+}
+
+// reportFormat returns the name of the report writer from -format or one of
+// the format flags (-xunitnet, -nunit ...)
+func reportFormat() string {
+	switch {
+	case args.format != "":
+		return args.format
+	case args.bambooOut:
+		return "bamboo"
+	case args.xunitnetOut:
+		return "xunitnet"
+	case args.xunitnet2:
+		return "xunitnet2"
+	case args.nunitOut:
+		return "nunit"
+	case args.trxOut:
+		return "trx"
+	case args.tapOut:
+		return "tap"
+	case args.teamcityOut:
+		return "teamcity"
+	case args.sonarOut:
+		return "sonar"
+	}
+	return "xunit"
+}
+
+// printFormats prints the available report formats
+func printFormats() {
+	fmt.Println("formats:")
+	for _, name := range lib.WriterNames() {
+		writer, _ := lib.GetWriter(name)
+		if d, ok := writer.(lib.Describer); ok {
+			fmt.Printf("  %-14s %s\n", name, d.Description())
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
 }
 
 func main() {
+	parseArgs()
+
 	if args.showVersion {
 		fmt.Printf("go2xunit %s\n", Version)
 		os.Exit(0)
 	}
 
+	if args.format == "help" {
+		printFormats()
+		os.Exit(0)
+	}
+
 	// No time ... prefix for error messages
 	log.SetFlags(0)
 
@@ -63,45 +349,48 @@ func main() {
 		log.Fatalf("error: %s", err)
 	}
 
-	input, output, err := getIO(args.inFile, args.outFile)
-	if err != nil {
-		log.Fatalf("error: %s", err)
+	if args.run {
+		os.Exit(runMain(flag.Args()))
 	}
 
-	// We'd like the test time to be the time of the generated file
-	var testTime time.Time
-	stat, err := input.Stat()
+	if args.merge {
+		os.Exit(mergeMain(args.files))
+	}
+
+	inputs, output, err := getIO(args.inFiles, args.outFile)
 	if err != nil {
-		testTime = time.Now()
-	} else {
-		testTime = stat.ModTime()
+		log.Fatalf("error: %s", err)
 	}
 
-	var parse func(rd io.Reader, suiteName string) (lib.Suites, error)
+	var suites lib.Suites
+	var testTime time.Time
+	for _, input := range inputs {
+		// We'd like the test time to be the time of the (latest) generated file
+		fileTime := time.Now()
+		if stat, err := input.Stat(); err == nil {
+			fileTime = stat.ModTime()
+		}
+		if fileTime.After(testTime) {
+			testTime = fileTime
+		}
 
-	if args.isGocheck {
-		parse = lib.ParseGocheck
-	} else {
-		parse = lib.ParseGotest
+		inSuites, err := parseInput(input, output)
+		if err != nil {
+			if len(inputs) > 1 {
+				err = fmt.Errorf("%s: %s", input.Name(), err)
+			}
+			log.Fatalf("error: %s", err)
+		}
+		suites = lib.MergeSuites(suites, inSuites)
+		input.Close()
 	}
 
-	suites, err := parse(input, args.suitePrefix)
-	if err != nil {
-		log.Fatalf("error: %s", err)
-	}
 	if len(suites) == 0 {
 		log.Fatalf("error: no tests found")
 		os.Exit(1)
 	}
 
-	xmlTemplate := lib.XUnitTemplate
-	if args.xunitnetOut {
-		xmlTemplate = lib.XUnitNetTemplate
-	} else if args.bambooOut || (len(suites) > 1) {
-		xmlTemplate = lib.XMLMultiTemplate
-	}
-
-	lib.WriteXML(suites, output, xmlTemplate, testTime)
+	writeReport(suites, output, testTime, len(inputs) > 1)
 	if args.fail && suites.HasFailures() {
 		os.Exit(1)
 	}
@@ -0,0 +1,44 @@
+// Merge xunit XML files
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/tebeka/go2xunit/lib"
+)
+
+// mergeMain merges xunit XML files to one report. It returns the exit code
+// for go2xunit.
+func mergeMain(files []string) int {
+	inputs, output, err := getIO(files, args.outFile)
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+
+	var suites lib.Suites
+	var testTime time.Time
+	for _, input := range inputs {
+		if stat, err := input.Stat(); err == nil && stat.ModTime().After(testTime) {
+			testTime = stat.ModTime()
+		}
+
+		inSuites, err := lib.ParseXUnit(input, args.suitePrefix)
+		if err != nil {
+			log.Fatalf("error: %s: %s", input.Name(), err)
+		}
+		suites = lib.MergeSuites(suites, inSuites)
+		input.Close()
+	}
+
+	if len(suites) == 0 {
+		log.Printf("error: no tests found")
+		return 1
+	}
+
+	writeReport(suites, output, testTime, true)
+	if args.fail && suites.HasFailures() {
+		return 1
+	}
+	return 0
+}
@@ -14,12 +14,26 @@ import (
 var (
 	// FIXME
 	ignored = map[string]bool{
-		"gotest-buildfailed.out": true,
-		"gocheck-nofiles.out":    true,
+		"gocheck-nofiles.out": true,
+	}
+
+	// Output file extension by type (default to .xml)
+	outExt = map[string]string{
+		"tap":      ".tap",
+		"teamcity": ".txt",
 	}
 
 	xTimeRe = regexp.MustCompile(`run-date="[^"]+" run-time="[^"]+"`)
 	xTime   = []byte(`run-date="2015-06-05" run-time="18:34:41"`)
+
+	nTimeRe = regexp.MustCompile(`(start|end)-time="[^"]+"`)
+	nTime   = []byte(`$1-time="2015-06-05 18:34:41Z"`)
+
+	hTimeRe = regexp.MustCompile(`<span>\d{4}-\d\d-\d\d \d\d:\d\d:\d\d</span>`)
+	hTime   = []byte(`<span>2015-06-05 18:34:41</span>`)
+
+	trxTimeRe = regexp.MustCompile(`(creation|queuing|start|finish|startTime|endTime)="[^"]+"`)
+	trxTime   = []byte(`$1="2015-06-05T18:34:41.0000000Z"`)
 )
 
 type fixFunc func([]byte) []byte
@@ -28,6 +42,18 @@ func fixXUnit(in []byte) []byte {
 	return xTimeRe.ReplaceAll(in, xTime)
 }
 
+func fixNUnit(in []byte) []byte {
+	return nTimeRe.ReplaceAll(in, nTime)
+}
+
+func fixTRX(in []byte) []byte {
+	return trxTimeRe.ReplaceAll(in, trxTime)
+}
+
+func fixHTML(in []byte) []byte {
+	return hTimeRe.ReplaceAll(in, hTime)
+}
+
 func checkRegression(t *testing.T, inFile, outFile string, args []string, fixer fixFunc) {
 	stdin, err := os.Open(inFile)
 	if err != nil {
@@ -80,7 +106,11 @@ func iterCheck(t *testing.T, inType, outType string, args []string, fixer fixFun
 		if ignored[base] {
 			continue
 		}
-		outFile := fmt.Sprintf(dataPath+"/out/%s/%s.xml", outType, base)
+		ext := outExt[outType]
+		if ext == "" {
+			ext = ".xml"
+		}
+		outFile := fmt.Sprintf(dataPath+"/out/%s/%s%s", outType, base, ext)
 		name := fmt.Sprintf("%s-%s", outType, inFile)
 		t.Run(name, func(t *testing.T) {
 			checkRegression(t, inFile, outFile, args, fixer)
@@ -88,14 +118,187 @@ func iterCheck(t *testing.T, inType, outType string, args []string, fixer fixFun
 	}
 }
 
-func TestRegression(t *testing.T) {
+func build(t *testing.T) {
 	cmd := exec.Command("go", "build")
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("can't build - %s", err)
 	}
+}
+
+func TestRegression(t *testing.T) {
+	build(t)
 
 	iterCheck(t, "gotest", "xunit", nil, nil)
 	iterCheck(t, "gocheck", "xunit", []string{"-gocheck"}, nil)
 	iterCheck(t, "gotest", "xunit.net", []string{"-xunitnet"}, fixXUnit)
 	iterCheck(t, "gocheck", "xunit.net", []string{"-gocheck", "-xunitnet"}, fixXUnit)
+	iterCheck(t, "json", "xunit", []string{"-json"}, nil)
+	iterCheck(t, "json", "xunit.net", []string{"-json", "-xunitnet"}, fixXUnit)
+	iterCheck(t, "gotest", "xunit.net2", []string{"-xunitnet2"}, fixXUnit)
+	iterCheck(t, "gocheck", "xunit.net2", []string{"-gocheck", "-xunitnet2"}, fixXUnit)
+	iterCheck(t, "json", "xunit.net2", []string{"-json", "-xunitnet2"}, fixXUnit)
+	iterCheck(t, "gotest", "nunit", []string{"-nunit"}, fixNUnit)
+	iterCheck(t, "gocheck", "nunit", []string{"-gocheck", "-nunit"}, fixNUnit)
+	iterCheck(t, "json", "nunit", []string{"-json", "-nunit"}, fixNUnit)
+	iterCheck(t, "gotest", "trx", []string{"-trx"}, fixTRX)
+	iterCheck(t, "gocheck", "trx", []string{"-gocheck", "-trx"}, fixTRX)
+	iterCheck(t, "json", "trx", []string{"-json", "-trx"}, fixTRX)
+	iterCheck(t, "gotest", "tap", []string{"-tap"}, nil)
+	iterCheck(t, "gocheck", "tap", []string{"-gocheck", "-tap"}, nil)
+	iterCheck(t, "json", "tap", []string{"-json", "-tap"}, nil)
+	iterCheck(t, "gotest", "teamcity", []string{"-teamcity"}, nil)
+	iterCheck(t, "gocheck", "teamcity", []string{"-gocheck", "-teamcity"}, nil)
+	iterCheck(t, "json", "teamcity", []string{"-json", "-teamcity"}, nil)
+	iterCheck(t, "gotest-deep", "xunit-nested", []string{"-nested"}, nil)
+	iterCheck(t, "json-deep", "xunit-nested", []string{"-json", "-nested"}, nil)
+	iterCheck(t, "json-deep", "xunit-nested", []string{"-json", "-format", "xunit-nested"}, nil)
+}
+
+func TestRun(t *testing.T) {
+	build(t)
+
+	inFile := dataPath + "/in/gotest-fail.out"
+	outFile := "/tmp/go2xunit-run.xml"
+	script := fmt.Sprintf("cat %s; exit 3", inFile)
+	cmd := exec.Command("./go2xunit", "run", "-output", outFile, "--", "sh", "-c", script)
+	out, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("bad exit status - %v", err)
+	}
+
+	input, err := ioutil.ReadFile(inFile)
+	if err != nil {
+		t.Fatalf("can't read %s - %s", inFile, err)
+	}
+	if !bytes.Equal(out, input) {
+		t.Fatalf("command output not copied to stdout")
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/xunit/gotest-fail.out.xml", nil)
+}
+
+func TestPassthrough(t *testing.T) {
+	build(t)
+
+	inFile := dataPath + "/in/gotest-fail.out"
+	outFile := "/tmp/go2xunit-passthrough.xml"
+	stdin, err := os.Open(inFile)
+	if err != nil {
+		t.Fatalf("can't open %s - %s", inFile, err)
+	}
+	defer stdin.Close()
+
+	cmd := exec.Command("./go2xunit", "-passthrough", "-output", outFile)
+	cmd.Stdin = stdin
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("error running on %s - %s", inFile, err)
+	}
+
+	input, err := ioutil.ReadFile(inFile)
+	if err != nil {
+		t.Fatalf("can't read %s - %s", inFile, err)
+	}
+	if !bytes.Equal(out, input) {
+		t.Fatalf("input not copied to stdout")
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/xunit/gotest-fail.out.xml", nil)
+}
+
+func TestMultipleInputs(t *testing.T) {
+	build(t)
+
+	outFile := "/tmp/go2xunit-merged.xml"
+	cmd := exec.Command("./go2xunit",
+		"-input", dataPath+"/in/gotest-1.[67].out",
+		"-input", dataPath+"/in/gotest-parallel.out",
+		"-output", outFile)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error running on multiple inputs - %s", err)
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/merged.xml", nil)
+}
+
+func TestMerge(t *testing.T) {
+	build(t)
+
+	outFile := "/tmp/go2xunit-merged-xml.xml"
+	cmd := exec.Command("./go2xunit", "merge",
+		dataPath+"/out/xunit/gotest-1.7.out.xml",
+		dataPath+"/nosetests.xml",
+		dataPath+"/out/merged.xml",
+		"-output", outFile)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error merging - %s", err)
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/merged-xml.xml", nil)
+}
+
+func TestMarkdown(t *testing.T) {
+	build(t)
+
+	outFile := "/tmp/go2xunit-summary.md"
+	cmd := exec.Command("./go2xunit", "-json",
+		"-input", dataPath+"/in/json-mixed.out",
+		"-input", dataPath+"/in/json-panic.out",
+		"-output", os.DevNull,
+		"-markdown", outFile)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error writing markdown - %s", err)
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/summary.md", nil)
+}
+
+func TestHTML(t *testing.T) {
+	build(t)
+
+	outFile := "/tmp/go2xunit-report.html"
+	cmd := exec.Command("./go2xunit", "-json",
+		"-input", dataPath+"/in/json-mixed.out",
+		"-input", dataPath+"/in/json-panic.out",
+		"-output", os.DevNull,
+		"-html", outFile)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error writing HTML - %s", err)
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/report.html", fixHTML)
+}
+
+func TestTemplate(t *testing.T) {
+	build(t)
+
+	outFile := "/tmp/go2xunit-custom.xml"
+	cmd := exec.Command("./go2xunit", "-json",
+		"-input", dataPath+"/in/json-mixed.out",
+		"-template", dataPath+"/custom.tmpl",
+		"-output", outFile)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("error writing with template - %s", err)
+	}
+
+	checkOutput(t, outFile, dataPath+"/out/custom.xml", nil)
+}
+
+func checkOutput(t *testing.T, outFile, expectedFile string, fixer fixFunc) {
+	out, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatalf("can't read %s - %s", outFile, err)
+	}
+	expected, err := ioutil.ReadFile(expectedFile)
+	if err != nil {
+		t.Fatalf("can't read %s - %s", expectedFile, err)
+	}
+	if fixer != nil {
+		out = fixer(out)
+		expected = fixer(expected)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Fatalf("%s - output mismatch\n\n%s", outFile, runDiff(out, expected))
+	}
 }
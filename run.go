@@ -0,0 +1,88 @@
+// Run tests and parse their output
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// runCommand starts argv with its output copied to console and returns a
+// reader of the output. The returned channel gets the command error (if any)
+// once it's done.
+func runCommand(argv []string, console io.Writer) (io.Reader, <-chan error, error) {
+	rd, wr := io.Pipe()
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(console, wr)
+	cmd.Stderr = cmd.Stdout
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		wr.Close()
+		done <- err
+	}()
+
+	return rd, done, nil
+}
+
+// exitCode returns the exit code of a command from the error returned by Wait
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// runMain runs argv, parses its output while it's running and writes the
+// report. It returns the exit code for go2xunit.
+func runMain(argv []string) int {
+	output, err := getOutput(args.outFile)
+	if err != nil {
+		log.Fatalf("error: can't open %s for writing: %s", args.outFile, err)
+	}
+
+	testTime := time.Now()
+	rd, done, err := runCommand(argv, getConsole(output))
+	if err != nil {
+		log.Fatalf("error: can't run %s - %s", argv[0], err)
+	}
+
+	parse := getParser(output)
+	suites, parseErr := parse(rd, args.suitePrefix)
+	// Drain output so the command won't block if parsing stopped early
+	io.Copy(ioutil.Discard, rd)
+	code := exitCode(<-done)
+
+	if parseErr != nil {
+		log.Printf("error: %s", parseErr)
+		return 1
+	}
+	if len(suites) == 0 {
+		log.Printf("error: no tests found")
+		if code == 0 {
+			code = 1
+		}
+		return code
+	}
+
+	writeReport(suites, output, testTime, false)
+	if code != 0 {
+		return code
+	}
+	if args.fail && suites.HasFailures() {
+		return 1
+	}
+	return 0
+}